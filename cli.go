@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"craft-hackathon/client"
+)
+
+// cliUsage lists the CLI commands
+const cliUsage = `Usage: craft <command> [flags]
+
+Commands:
+  serve     run the HTTP server (the default)
+  fetch     print a block tree as JSON or markdown
+  search    search the document with a regular expression
+  insert    insert markdown into the document
+
+Settings are read from the JSON file named by CONFIG_FILE, if set, and
+overridden by CRAFT_BASE_URL, CRAFT_AUTH_TOKEN, CRAFT_TIMEOUT,
+RETRY_MAX_ATTEMPTS, RETRY_JITTER and LISTEN_ADDR. The server also reads
+MAX_BODY_BYTES, JOB_WORKERS, JOB_QUEUE_SIZE, API_TOKENS, TIMESTAMP_FORMAT,
+TIMEZONE and CORS_ALLOWED_ORIGINS, _METHODS and _HEADERS.
+`
+
+// runCLI runs a single CLI command and returns the process exit code
+func runCLI(c *client.Client, args []string, stdout, stderr io.Writer) int {
+	var err error
+	switch args[0] {
+	case "fetch":
+		err = cliFetch(c, args[1:], stdout, stderr)
+	case "search":
+		err = cliSearch(c, args[1:], stdout, stderr)
+	case "insert":
+		err = cliInsert(c, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "craft %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// cliFetch prints a block tree, or a summary of its top-level blocks
+func cliFetch(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	id := flags.String("id", "", "block ID to fetch (default the document root)")
+	depth := flags.Int("depth", -1, "maximum depth, -1 for the whole tree")
+	format := flags.String("format", "json", `output format: "json", "jsonl", "markdown" or "summary"`)
+	metadata := flags.Bool("metadata", false, "include creation and modification metadata")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "markdown" {
+		md, err := c.FetchBlocksMarkdown(*id, *depth)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, md)
+		return nil
+	}
+
+	block, err := c.FetchBlocks(*id, *depth, *metadata)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		data, err := block.ToJSON(true)
+		if err != nil {
+			return fmt.Errorf("encoding blocks: %w", err)
+		}
+		fmt.Fprintln(stdout, string(data))
+	case "jsonl":
+		if err := client.StreamJSONL(block, stdout); err != nil {
+			return err
+		}
+	case "summary":
+		fmt.Fprintf(stdout, "%s (%s) %s\n", block.ID, block.Type, truncate(block.Markdown, 80))
+		for i, child := range block.Content {
+			fmt.Fprintf(stdout, "  [%d] %s (%s) %s\n", i, child.ID, child.Type, truncate(child.Markdown, 80))
+		}
+		fmt.Fprintf(stdout, "%d blocks in total\n", countBlocks(block))
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return nil
+}
+
+// cliSearch prints the blocks matching a pattern
+func cliSearch(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	caseSensitive := flags.Bool("case", false, "match case")
+	before := flags.Int("before", 0, "context blocks before each match")
+	after := flags.Int("after", 0, "context blocks after each match")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one pattern, got %d arguments", flags.NArg())
+	}
+
+	matches, err := c.Search(flags.Arg(0), *caseSensitive, *before, *after)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		fmt.Fprintf(stdout, "%s: %s\n", match.BlockID, truncate(match.Markdown, 80))
+	}
+	return nil
+}
+
+// cliInsert inserts markdown from the arguments, or from stdin when there
+// are none
+func cliInsert(c *client.Client, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("insert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pageID := flags.String("page", "", "page to insert into (default the document root)")
+	position := flags.String("position", "end", `"start" or "end" of the page, or "before"/"after" the sibling`)
+	siblingID := flags.String("sibling", "", "sibling block for before/after positions")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	markdown := strings.Join(flags.Args(), " ")
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+		markdown = string(data)
+	}
+	if strings.TrimSpace(markdown) == "" {
+		return fmt.Errorf("nothing to insert")
+	}
+
+	req := QueryRequest{Query: markdown, PageID: *pageID, Position: *position, SiblingID: *siblingID}
+	insert, err := req.insertRequest(markdown)
+	if err != nil {
+		return err
+	}
+	if insert.Position, err = withRootPage(insert.Position, c.RootPageID); err != nil {
+		return err
+	}
+
+	inserted, err := c.InsertBlocks(insert)
+	if err != nil {
+		return err
+	}
+	for _, block := range inserted {
+		fmt.Fprintln(stdout, block.ID)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package client
+
+// CraftAPI is the set of Craft API operations shared by Client and
+// FakeClient, so code using the API can be tested against the fake
+type CraftAPI interface {
+	FetchBlocks(id string, maxDepth int, fetchMetadata bool) (*Block, error)
+	FetchBlocksMarkdown(id string, maxDepth int) (string, error)
+	InsertBlocks(req InsertRequest) ([]Block, error)
+	UpdateBlocks(req UpdateRequest) ([]Block, error)
+	DeleteBlocks(blockIDs []string) ([]string, error)
+	MoveBlocks(req MoveRequest) ([]string, error)
+	Search(pattern string, caseSensitive bool, beforeCount, afterCount int) ([]SearchMatch, error)
+	GenerateUploadURL(fileName, mimeType string) (*UploadLinkResponse, error)
+}
+
+var (
+	_ CraftAPI = (*Client)(nil)
+	_ CraftAPI = (*FakeClient)(nil)
+)
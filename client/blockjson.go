@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// blockFields has the same fields as Block but none of its methods, so the
+// custom JSON methods can use the default encoding without recursing
+type blockFields Block
+
+// knownBlockFields holds the JSON names of the fields Block models
+var knownBlockFields = func() map[string]bool {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(Block{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	return known
+}()
+
+// UnmarshalJSON decodes a block, keeping unmodeled fields in Extra
+func (b *Block) UnmarshalJSON(data []byte) error {
+	var fields blockFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for name := range all {
+		if knownBlockFields[name] {
+			delete(all, name)
+		}
+	}
+
+	*b = Block(fields)
+	if len(all) > 0 {
+		b.Extra = all
+	}
+	return nil
+}
+
+// MarshalJSON encodes a block along with the unmodeled fields in Extra.
+// Modeled fields take precedence over Extra entries of the same name.
+func (b Block) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(blockFields(b))
+	if err != nil || len(b.Extra) == 0 {
+		return data, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name, value := range b.Extra {
+		if !knownBlockFields[name] {
+			all[name] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+// ToJSON encodes the block, indented by two spaces when indent is set
+func (b *Block) ToJSON(indent bool) ([]byte, error) {
+	if indent {
+		return json.MarshalIndent(b, "", "  ")
+	}
+	return json.Marshal(b)
+}
+
+// StreamJSONL writes the tree to w as JSON Lines, one block per line in
+// depth-first order. Each line holds the block's own fields without its
+// content, plus a "path" field listing the IDs of its ancestors from the
+// root down.
+func StreamJSONL(root *Block, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	var ancestors []string
+	return root.Walk(0, func(block *Block, depth int) error {
+		ancestors = ancestors[:depth]
+		flat := *block
+		flat.Content = nil
+
+		data, err := json.Marshal(flat)
+		if err != nil {
+			return fmt.Errorf("encoding block %s: %w", block.ID, err)
+		}
+		var line map[string]json.RawMessage
+		if err := json.Unmarshal(data, &line); err != nil {
+			return fmt.Errorf("encoding block %s: %w", block.ID, err)
+		}
+		path, err := json.Marshal(append([]string{}, ancestors...))
+		if err != nil {
+			return fmt.Errorf("encoding block %s: %w", block.ID, err)
+		}
+		line["path"] = path
+
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("writing block %s: %w", block.ID, err)
+		}
+		ancestors = append(ancestors, block.ID)
+		return nil
+	})
+}
+
+// Project zeroes every field of the block and its descendants whose JSON
+// name is not in fields, including unmodeled ones in Extra, to shrink what
+// gets serialized. Content is always kept so the tree shape survives.
+func (b *Block) Project(fields []string) {
+	keep := make(map[string]bool, len(fields))
+	for _, name := range fields {
+		keep[name] = true
+	}
+	b.project(keep)
+}
+
+func (b *Block) project(keep map[string]bool) {
+	v := reflect.ValueOf(b).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || name == "content" || keep[name] {
+			continue
+		}
+		v.Field(i).SetZero()
+	}
+
+	for name := range b.Extra {
+		if !keep[name] {
+			delete(b.Extra, name)
+		}
+	}
+	if len(b.Extra) == 0 {
+		b.Extra = nil
+	}
+
+	for i := range b.Content {
+		b.Content[i].project(keep)
+	}
+}
+
+// decode decodes a response body into v, rejecting unknown fields when
+// strict decoding is on
+func (c *Client) decode(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	if c.strictDecoding {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if c.strictDecoding {
+		return checkUnknownFields(v)
+	}
+	return nil
+}
+
+// unmarshal is decode for data already read
+func (c *Client) unmarshal(data []byte, v any) error {
+	return c.decode(bytes.NewReader(data), v)
+}
+
+// checkUnknownFields reports the first unmodeled field kept in the Extra
+// of a decoded block, since Block's own decoding accepts them
+func checkUnknownFields(v any) error {
+	var blocks []Block
+	switch v := v.(type) {
+	case *Block:
+		blocks = []Block{*v}
+	case *[]Block:
+		blocks = *v
+	}
+
+	for i := range blocks {
+		for _, block := range blocks[i].Flatten() {
+			for name := range block.Extra {
+				return fmt.Errorf("json: unknown field %q in block %s", name, block.ID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The responses in testdata are the examples from docs/craft-docs.md, with
+// the metadata fields fetchMetadata adds filled in on a few blocks
+
+// TestResponseRoundTrip decodes each response into the client's types and
+// encodes it again, failing if any field was dropped or renamed on the way
+func TestResponseRoundTrip(t *testing.T) {
+	tests := []struct {
+		file string
+		into func() any
+	}{
+		{"fetch_blocks.json", func() any { return new(Block) }},
+		{"search.json", func() any {
+			return new(struct {
+				Items []SearchMatch `json:"items"`
+			})
+		}},
+		{"upload_link.json", func() any { return new(UploadLinkResponse) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := tt.into()
+			if err := json.Unmarshal(data, v); err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+
+			var want, got any
+			if err := json.Unmarshal(data, &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(encoded, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the response:\ngot  %s\nwant %s", encoded, data)
+			}
+		})
+	}
+}
+
+// TestBlockModelsDocumentedFields checks that only fields Block deliberately
+// leaves unmodeled end up in Extra
+func TestBlockModelsDocumentedFields(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fetch_blocks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root Block
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+
+	unmodeled := map[string]bool{"comments": true}
+	for _, block := range root.Flatten() {
+		for name := range block.Extra {
+			if !unmodeled[name] {
+				t.Errorf("block %s: field %q is not modeled", block.ID, name)
+			}
+		}
+	}
+
+	second := root.FindByID("1")
+	if second == nil || second.Author != "Ada Lovelace" || second.ModifiedBy != "Grace Hopper" ||
+		second.CreatedAt == nil || second.ModifiedAt == nil {
+		t.Errorf("metadata of block 1 not decoded: %+v", second)
+	}
+}
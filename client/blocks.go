@@ -0,0 +1,169 @@
+package client
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// pageTagPattern matches the structural tags wrapping a page title, such as
+// <page>Title</page> or <card>Title</card>
+var pageTagPattern = regexp.MustCompile(`(?s)^<[a-zA-Z]+>(.*)</[a-zA-Z]+>$`)
+
+// NewCodeBlock creates a code block with the given source and language
+func NewCodeBlock(code, language string) Block {
+	return Block{
+		Type:     "code",
+		Markdown: code,
+		Language: language,
+	}
+}
+
+// NewPageBlock creates a page block with the given title
+func NewPageBlock(title string) Block {
+	return Block{
+		Type:      "page",
+		TextStyle: "page",
+		Markdown:  "<page>" + title + "</page>",
+	}
+}
+
+// NewToggleBlock creates a collapsible block with the given title and children
+func NewToggleBlock(title string, children []Block, collapsed bool) Block {
+	return Block{
+		Type:      "toggle",
+		Markdown:  title,
+		Content:   children,
+		Collapsed: &collapsed,
+	}
+}
+
+// NewTableBlock creates a table block; the first row is the header
+func NewTableBlock(rows [][]string) Block {
+	return Block{
+		Type: "table",
+		Rows: rows,
+	}
+}
+
+// NewDividerBlock creates a horizontal divider
+func NewDividerBlock() Block {
+	return Block{Type: "divider"}
+}
+
+// NewQuoteBlock creates a block quote
+func NewQuoteBlock(md string) Block {
+	return Block{
+		Type:      "text",
+		TextStyle: "quote",
+		Markdown:  md,
+	}
+}
+
+// NewCalloutBlock creates a highlighted callout, led by emoji if one is
+// given
+func NewCalloutBlock(md, emoji string) Block {
+	if emoji != "" {
+		md = emoji + " " + md
+	}
+	return Block{
+		Type:      "text",
+		TextStyle: "callout",
+		Markdown:  md,
+	}
+}
+
+// cloneBlock returns a deep copy of the block and its descendants
+func cloneBlock(block Block) Block {
+	if block.Collapsed != nil {
+		collapsed := *block.Collapsed
+		block.Collapsed = &collapsed
+	}
+	if block.Rows != nil {
+		rows := make([][]string, len(block.Rows))
+		for i, row := range block.Rows {
+			rows[i] = append([]string(nil), row...)
+		}
+		block.Rows = rows
+	}
+	if block.Extra != nil {
+		extra := make(map[string]json.RawMessage, len(block.Extra))
+		for name, value := range block.Extra {
+			extra[name] = value
+		}
+		block.Extra = extra
+	}
+	if block.Content != nil {
+		content := make([]Block, len(block.Content))
+		for i, child := range block.Content {
+			content[i] = cloneBlock(child)
+		}
+		block.Content = content
+	}
+	return block
+}
+
+// copyWithoutIDs returns a deep copy of the block with IDs cleared on it and
+// every descendant, along with the read-only file and metadata fields, so it
+// can be inserted as new content
+func copyWithoutIDs(block Block) Block {
+	block = cloneBlock(block)
+	clearIDs(&block)
+	return block
+}
+
+// clearIDs removes IDs and read-only fields throughout the tree
+func clearIDs(block *Block) {
+	block.ID = ""
+	block.MimeType = ""
+	block.FileSize = 0
+	block.CreatedAt = nil
+	block.ModifiedAt = nil
+	block.Author = ""
+	block.ModifiedBy = ""
+	for i := range block.Content {
+		clearIDs(&block.Content[i])
+	}
+}
+
+// PageTitle returns the title of a page block with its structural tags
+// removed
+func PageTitle(block *Block) string {
+	md := strings.TrimSpace(block.Markdown)
+	if m := pageTagPattern.FindStringSubmatch(md); m != nil {
+		return m[1]
+	}
+	return md
+}
+
+// NormalizeIndentation rewrites indentation levels across the tree so each
+// block is indented at most one level deeper than the block before it,
+// closing gaps such as 0, 2, 3 into 0, 1, 2
+func (b *Block) NormalizeIndentation() {
+	normalizeIndentation(b.Content)
+}
+
+// normalizeIndentation fixes the indentation of a run of sibling blocks and
+// recurses into their children
+func normalizeIndentation(blocks []Block) {
+	// open holds the enclosing list levels of the current block, keeping
+	// both the original and the normalized level of each
+	type level struct{ original, normalized int }
+	var open []level
+
+	for i := range blocks {
+		block := &blocks[i]
+		for len(open) > 0 && open[len(open)-1].original >= block.IndentationLevel {
+			open = open[:len(open)-1]
+		}
+
+		normalized := 0
+		if len(open) > 0 {
+			normalized = open[len(open)-1].normalized + 1
+		}
+		open = append(open, level{original: block.IndentationLevel, normalized: normalized})
+		block.IndentationLevel = normalized
+
+		normalizeIndentation(block.Content)
+	}
+}
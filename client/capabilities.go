@@ -0,0 +1,118 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+)
+
+// ErrUnsupported is returned early by methods whose feature the API was
+// found not to support by Capabilities
+var ErrUnsupported = errors.New("not supported by the API")
+
+// APICapabilities reports which features the connected API supports
+type APICapabilities struct {
+	Markdown bool  // fetching blocks as markdown
+	Metadata bool  // fetchMetadata timestamps and authors
+	Search   bool  // GET /blocks/search
+	Upload   *bool // POST /upload-link; nil until an upload link is requested
+}
+
+// capabilityCache holds the result of the last Capabilities probe and of
+// the last upload link request. It sits behind a pointer so copying a
+// Client does not copy the lock.
+type capabilityCache struct {
+	mu     sync.Mutex
+	caps   *APICapabilities // Upload is kept apart in upload
+	upload *bool
+}
+
+// Capabilities probes the API for the features it supports. The API has no
+// version or capabilities endpoint, so each feature is tried with a
+// request: a shallow fetch as markdown and with metadata, and a search for
+// a pattern that matches nothing. Markdown counts as supported when the
+// response comes back as text/markdown or text/plain rather than JSON.
+// Uploads cannot be probed without creating an upload link, so Upload
+// reports the outcome of the last GenerateUploadURL call and is nil before
+// one. Once probed, Search, FetchBlocksMarkdown and metadata fetches fail
+// fast with ErrUnsupported for missing features.
+func (c *Client) Capabilities() (*APICapabilities, error) {
+	caps := &APICapabilities{}
+
+	_, contentType, err := c.fetchBlocksMarkdown("", 0)
+	if caps.Markdown, err = probeResult(err); err != nil {
+		return nil, fmt.Errorf("probing markdown: %w", err)
+	}
+	if caps.Markdown {
+		// JSON comes back when the Accept header is ignored
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		caps.Markdown = mediaType == "text/markdown" || mediaType == "text/plain"
+	}
+
+	_, err = c.fetchBlocks("", 0, true)
+	if caps.Metadata, err = probeResult(err); err != nil {
+		return nil, fmt.Errorf("probing metadata: %w", err)
+	}
+
+	_, err = c.search("capabilities-probe-3f9c1e", true, 0, 0)
+	if caps.Search, err = probeResult(err); err != nil {
+		return nil, fmt.Errorf("probing search: %w", err)
+	}
+
+	copied := *caps
+	// A Client built without NewClient has nowhere to keep the result
+	if c.capabilities != nil {
+		c.capabilities.mu.Lock()
+		defer c.capabilities.mu.Unlock()
+		c.capabilities.caps = caps
+		if upload := c.capabilities.upload; upload != nil {
+			supported := *upload
+			copied.Upload = &supported
+		}
+	}
+	return &copied, nil
+}
+
+// recordUpload keeps whether an upload link request found the endpoint, for
+// Capabilities to report. An error that does not say the endpoint is
+// missing leaves the last outcome in place.
+func (cc *capabilityCache) recordUpload(err error) {
+	supported, err := probeResult(err)
+	if cc == nil || err != nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.upload = &supported
+}
+
+// probeResult turns the error of a probe request into whether the feature
+// is supported. Statuses meaning the endpoint or method does not exist
+// count as unsupported; other errors are returned.
+func probeResult(err error) (bool, error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusNotImplemented:
+			return false, nil
+		}
+	}
+	return err == nil, err
+}
+
+// checkSupported returns ErrUnsupported if a previous Capabilities probe
+// found the feature missing, and nil if it was found or never probed
+func (c *Client) checkSupported(feature string, supported func(*APICapabilities) bool) error {
+	if c.capabilities == nil {
+		return nil // a Client built without NewClient
+	}
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+
+	if c.capabilities.caps != nil && !supported(c.capabilities.caps) {
+		return fmt.Errorf("%s: %w", feature, ErrUnsupported)
+	}
+	return nil
+}
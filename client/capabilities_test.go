@@ -0,0 +1,60 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	// markdownType is the Content-Type the server gives markdown fetches
+	markdownType := "application/json"
+	uploadLinks := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blocks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/markdown" {
+			w.Header().Set("Content-Type", markdownType)
+			w.Write([]byte("# Doc\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Block{ID: "root"})
+	})
+	mux.HandleFunc("POST /upload-link", func(w http.ResponseWriter, r *http.Request) {
+		uploadLinks++
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL)
+
+	caps, err := c.Capabilities()
+	if err != nil {
+		t.Fatalf("Capabilities: %v", err)
+	}
+	want := APICapabilities{Metadata: true}
+	if *caps != want {
+		t.Errorf("Capabilities = %+v, want %+v", *caps, want)
+	}
+	if uploadLinks != 0 {
+		t.Errorf("Capabilities requested %d upload links, want none", uploadLinks)
+	}
+	if _, err := c.FetchBlocksMarkdown("", 0); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("FetchBlocksMarkdown after probe: err = %v, want ErrUnsupported", err)
+	}
+
+	// A new probe is not short-circuited by the last one
+	markdownType = "text/markdown; charset=utf-8"
+	if _, err := c.GenerateUploadURL("a.txt", ""); err == nil {
+		t.Fatal("GenerateUploadURL succeeded, want a 404")
+	}
+	caps, err = c.Capabilities()
+	if err != nil {
+		t.Fatalf("Capabilities: %v", err)
+	}
+	if !caps.Markdown || caps.Search || caps.Upload == nil || *caps.Upload {
+		t.Errorf("Capabilities = %+v, want markdown, no search and upload known to be missing", *caps)
+	}
+}
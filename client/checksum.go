@@ -0,0 +1,41 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// Checksum returns a hex-encoded SHA-256 fingerprint of the tree's content:
+// block types, styles, markdown, media, table rows and the order of it all.
+// IDs, metadata and unmodeled API fields are ignored, so a fetched tree and
+// a locally built copy of it hash the same.
+func (b *Block) Checksum() string {
+	h := sha256.New()
+	hashBlock(h, b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashBlock writes the content fields of block and its descendants to h.
+// Strings are quoted and each block records its child count, so different
+// trees cannot produce the same stream.
+func hashBlock(h hash.Hash, block *Block) {
+	collapsed := "-"
+	if block.Collapsed != nil {
+		collapsed = fmt.Sprint(*block.Collapsed)
+	}
+	fmt.Fprintf(h, "%q %q %q %d %q %d %q %q %q %q %d %d %q %q %d %q %s %d\n",
+		block.Type, block.TextStyle, block.Markdown, block.IndentationLevel,
+		block.ListStyle, block.ListStart, block.Font, block.Color, block.URL,
+		block.AltText, block.Width, block.Height, block.FileName, block.MimeType,
+		block.FileSize, block.Language, collapsed, len(block.Rows))
+	for _, row := range block.Rows {
+		fmt.Fprintf(h, "%d %q\n", len(row), row)
+	}
+
+	fmt.Fprintf(h, "%d\n", len(block.Content))
+	for i := range block.Content {
+		hashBlock(h, &block.Content[i])
+	}
+}
@@ -2,46 +2,215 @@ package client
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// Client represents the Craft API client
+// Version is the version of this client library
+const Version = "0.1.0"
+
+// DefaultUserAgent is sent with every request unless overridden
+const DefaultUserAgent = "craft-hackathon-client/" + Version
+
+// Default connection pool settings, sized above Go's defaults because the
+// client makes many calls to the same host
+const (
+	DefaultMaxIdleConns        = 256
+	DefaultMaxIdleConnsPerHost = 64
+	DefaultIdleConnTimeout     = 120 * time.Second
+)
+
+// DefaultMaxResponseBytes is the default cap on response body size
+const DefaultMaxResponseBytes = 32 << 20
+
+// ErrResponseTooLarge is returned when a response body exceeds the client's
+// MaxResponseBytes
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// Client represents the Craft API client. A Client is safe for concurrent
+// use by multiple goroutines; its exported fields must not be changed while
+// requests are in flight.
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+
+	// MaxResponseBytes caps the size of response bodies read by the client.
+	// Zero or a negative value disables the limit.
+	MaxResponseBytes int64
+
+	userAgent          string
+	traceLogger        *log.Logger
+	logTimestampFormat string
+	strictTemplates    bool
+	strictDecoding     bool
+	retry              RetryPolicy
+	progress           ProgressFunc
+	capabilities       *capabilityCache
+	root               *rootCache
+	maxTreeDepth       int
+	authToken          string
+	normalizeHeadings  bool
 }
 
 // NewClient creates a new Craft API client
-func NewClient(baseURL string) *Client {
-	return &Client{
-		BaseURL:    baseURL,
-		HTTPClient: &http.Client{},
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		BaseURL:            baseURL,
+		HTTPClient:         &http.Client{Transport: newTransport()},
+		MaxResponseBytes:   DefaultMaxResponseBytes,
+		userAgent:          DefaultUserAgent,
+		logTimestampFormat: time.RFC3339,
+		retry:              DefaultRetryPolicy,
+		capabilities:       &capabilityCache{},
+		root:               &rootCache{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// newTransport returns an HTTP/2-capable transport with the default pool
+// settings
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = DefaultMaxIdleConns
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	return transport
+}
+
+// Close releases idle connections held by the client's transport. The
+// client must not be used after Close.
+func (c *Client) Close() error {
+	c.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// newRequest creates an HTTP request carrying the headers common to all
+// API calls
+func (c *Client) newRequest(method, reqURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	// Only API calls are authenticated; pre-signed upload and file URLs
+	// carry their own credentials and reject a second set
+	if c.authToken != "" && c.isAPIURL(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
 	}
+	return req, nil
+}
+
+// isAPIURL reports whether u is on the API: the same scheme and host as
+// BaseURL, and BaseURL's path or a path below it
+func (c *Client) isAPIURL(u *url.URL) bool {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(u.Scheme, base.Scheme) || !strings.EqualFold(u.Host, base.Host) {
+		return false
+	}
+	prefix := strings.TrimSuffix(base.Path, "/")
+	return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
+
+// do executes a request, limiting the response body to MaxResponseBytes
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxResponseBytes > 0 {
+		resp.Body = &limitedBody{
+			Reader: io.LimitReader(resp.Body, c.MaxResponseBytes+1),
+			Closer: resp.Body,
+			limit:  c.MaxResponseBytes,
+		}
+	}
+	return resp, nil
+}
+
+// send executes a request with tracing but without limiting the response
+// body, for file transfers
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	var trace *requestTrace
+	if c.traceLogger != nil {
+		trace = newRequestTrace()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if trace != nil {
+		c.traceLogger.Printf("%s %s: %s", req.Method, req.URL.Path, trace)
+	}
+	return resp, err
+}
+
+// limitedBody reads at most one byte past its limit so it can tell a body
+// that fits exactly from one that was cut off
+type limitedBody struct {
+	io.Reader
+	io.Closer
+	read  int64
+	limit int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		// Reads after the first one past the limit return nothing
+		return max(n-int(b.read-b.limit), 0), fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, b.limit)
+	}
+	return n, err
 }
 
 // Block represents a content block in Craft
 type Block struct {
-	ID               string   `json:"id,omitempty"`
-	Type             string   `json:"type"`
-	TextStyle        string   `json:"textStyle,omitempty"`
-	Markdown         string   `json:"markdown,omitempty"`
-	Content          []Block  `json:"content,omitempty"`
-	IndentationLevel int      `json:"indentationLevel,omitempty"`
-	ListStyle        string   `json:"listStyle,omitempty"`
-	Font             string   `json:"font,omitempty"`
-	Color            string   `json:"color,omitempty"`
-	URL              string   `json:"url,omitempty"`
-	AltText          string   `json:"altText,omitempty"`
-	Width            any      `json:"width,omitempty"` // Can be int or string like "auto"
-	Height           int      `json:"height,omitempty"`
-	FileName         string   `json:"fileName,omitempty"`
-	MimeType         string   `json:"mimeType,omitempty"`
-	FileSize         int64    `json:"fileSize,omitempty"`
+	ID               string     `json:"id,omitempty"`
+	Type             string     `json:"type"`
+	TextStyle        string     `json:"textStyle,omitempty"`
+	Markdown         string     `json:"markdown,omitempty"`
+	Content          []Block    `json:"content,omitempty"`
+	IndentationLevel int        `json:"indentationLevel,omitempty"`
+	ListStyle        string     `json:"listStyle,omitempty"`
+	ListStart        int        `json:"listStart,omitempty"` // Number of the first item in a numbered list
+	Font             string     `json:"font,omitempty"`
+	Color            string     `json:"color,omitempty"`
+	URL              string     `json:"url,omitempty"`
+	AltText          string     `json:"altText,omitempty"`
+	Width            Dimension  `json:"width,omitempty"` // Pixels or AutoWidth()
+	Height           int        `json:"height,omitempty"`
+	FileName         string     `json:"fileName,omitempty"`
+	MimeType         string     `json:"mimeType,omitempty"`
+	FileSize         int64      `json:"fileSize,omitempty"`
+	Language         string     `json:"language,omitempty"`       // Code blocks only
+	Collapsed        *bool      `json:"collapsed,omitempty"`      // Toggle blocks only
+	Rows             [][]string `json:"rows,omitempty"`           // Table blocks only; first row is the header
+	CreatedAt        *Timestamp `json:"createdAt,omitempty"`      // Requires fetchMetadata
+	ModifiedAt       *Timestamp `json:"lastModifiedAt,omitempty"` // Requires fetchMetadata
+	Author           string     `json:"createdBy,omitempty"`      // Requires fetchMetadata
+	ModifiedBy       string     `json:"lastModifiedBy,omitempty"` // Requires fetchMetadata
+
+	// Extra holds fields returned by the API that Block does not model, such
+	// as metadata comments, so they survive a fetch, edit and update round
+	// trip
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Position specifies where to insert blocks
@@ -51,6 +220,30 @@ type Position struct {
 	SiblingID string `json:"siblingId,omitempty"` // For before/after positions
 }
 
+// defaultPosition checks a position given to a method that inserts into
+// pageID with InsertRequestFromMarkdown. An empty position means the end of
+// pageID, and a start or end position without a page ID targets pageID.
+func defaultPosition(pageID string, p Position) (Position, error) {
+	if p.PageID != "" && p.SiblingID != "" {
+		return p, errors.New("position has both a page ID and a sibling ID")
+	}
+	req, err := InsertRequestFromMarkdown(cmp.Or(p.SiblingID, p.PageID), "", p.Position)
+	if err != nil {
+		return p, err
+	}
+	pos := req.Position
+	if pos.SiblingID != p.SiblingID {
+		if p.SiblingID != "" {
+			return p, fmt.Errorf("position %q takes a page ID, not a sibling ID", pos.Position)
+		}
+		return p, fmt.Errorf("position %q takes a sibling ID, not a page ID", pos.Position)
+	}
+	if pos.PageID == "" && pos.SiblingID == "" {
+		pos.PageID = pageID
+	}
+	return pos, nil
+}
+
 // InsertRequest represents a request to insert blocks
 type InsertRequest struct {
 	Blocks   []Block  `json:"blocks,omitempty"`
@@ -58,6 +251,30 @@ type InsertRequest struct {
 	Position Position `json:"position"`
 }
 
+// InsertRequestFromMarkdown builds a request inserting markdown at position:
+// "start" or "end" of page pageID, or "before" or "after" block pageID. An
+// empty position means "end". Before and after need an ID; start and end
+// without one leave the page for the caller to fill in, and any other
+// position is an error.
+func InsertRequestFromMarkdown(pageID, markdown string, position string) (InsertRequest, error) {
+	req := InsertRequest{Markdown: markdown, Position: Position{Position: position}}
+	switch position {
+	case "":
+		req.Position.Position = "end"
+		fallthrough
+	case "start", "end":
+		req.Position.PageID = pageID
+	case "before", "after":
+		if pageID == "" {
+			return InsertRequest{}, fmt.Errorf("position %q needs a sibling ID", position)
+		}
+		req.Position.SiblingID = pageID
+	default:
+		return InsertRequest{}, fmt.Errorf("invalid position %q", position)
+	}
+	return req, nil
+}
+
 // UpdateRequest represents a request to update blocks
 type UpdateRequest struct {
 	Blocks []Block `json:"blocks"`
@@ -112,8 +329,20 @@ type UploadLinkResponse struct {
 	RawURL    string `json:"rawUrl"`
 }
 
-// FetchBlocks retrieves blocks from the document
+// FetchBlocks retrieves blocks from the document. The tree is checked with
+// CheckTree, so the tree helpers cannot recurse without bound on it.
 func (c *Client) FetchBlocks(id string, maxDepth int, fetchMetadata bool) (*Block, error) {
+	if fetchMetadata {
+		if err := c.checkSupported("metadata", func(caps *APICapabilities) bool { return caps.Metadata }); err != nil {
+			return nil, err
+		}
+	}
+	return c.fetchBlocks(id, maxDepth, fetchMetadata)
+}
+
+// fetchBlocks is FetchBlocks without the check against Capabilities, for
+// probing
+func (c *Client) fetchBlocks(id string, maxDepth int, fetchMetadata bool) (*Block, error) {
 	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
 
 	params := url.Values{}
@@ -131,33 +360,184 @@ func (c *Client) FetchBlocks(id string, maxDepth int, fetchMetadata bool) (*Bloc
 		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
 	}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := c.newRequest("GET", reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	var block Block
-	if err := json.NewDecoder(resp.Body).Decode(&block); err != nil {
+	body := withProgress(resp.Body, resp.ContentLength, c.progress)
+	if err := c.decode(body, &block); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
+	if err := block.CheckTree(c.maxTreeDepth); err != nil {
+		return nil, fmt.Errorf("checking response: %w", err)
+	}
 
 	return &block, nil
 }
 
+// FetchBlocksRaw retrieves blocks as undecoded JSON, keeping any fields the
+// Block struct does not model
+func (c *Client) FetchBlocksRaw(id string, maxDepth int) (json.RawMessage, error) {
+	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
+
+	params := url.Values{}
+	if id != "" {
+		params.Add("id", id)
+	}
+	if maxDepth != -1 {
+		params.Add("maxDepth", strconv.Itoa(maxDepth))
+	}
+
+	if len(params) > 0 {
+		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
+	}
+
+	req, err := c.newRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("response is not valid JSON")
+	}
+
+	return json.RawMessage(body), nil
+}
+
+// DocumentSize returns the size in bytes of the JSON response for the full
+// block tree under id, as reported by the Content-Length of a HEAD request.
+// If the API does not support HEAD or omits the length, the tree is fetched
+// and counted without being decoded or kept.
+func (c *Client) DocumentSize(id string) (int64, error) {
+	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
+	if id != "" {
+		reqURL = fmt.Sprintf("%s?%s", reqURL, url.Values{"id": {id}}.Encode())
+	}
+
+	req, err := c.newRequest("HEAD", reqURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("executing request: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK && resp.ContentLength >= 0:
+		return resp.ContentLength, nil
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented:
+		return 0, newAPIError(resp)
+	}
+
+	req, err = c.newRequest("GET", reqURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// The body is only counted, so it is not held to MaxResponseBytes
+	resp, err = c.send(req)
+	if err != nil {
+		return 0, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newSentAPIError(resp)
+	}
+
+	size, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("reading response: %w", err)
+	}
+	return size, nil
+}
+
+// FetchBlocksProgressive fetches blocks one level deeper at a time, starting
+// at depth 1, and passes each result to fn. It stops when fn asks to stop,
+// when fn returns an error, or once the whole subtree has been fetched.
+func (c *Client) FetchBlocksProgressive(id string, fn func(depth int, b *Block) (stop bool, err error)) error {
+	for depth := 1; ; depth++ {
+		block, err := c.FetchBlocks(id, depth, false)
+		if err != nil {
+			return fmt.Errorf("fetching depth %d: %w", depth, err)
+		}
+
+		stop, err := fn(depth, block)
+		if err != nil {
+			return err
+		}
+		if stop {
+			return nil
+		}
+
+		// A tree shallower than the requested depth was not truncated,
+		// so fetching deeper would return the same blocks again
+		levels, err := treeDepth(block, c.maxTreeDepth)
+		if err != nil {
+			return err
+		}
+		if levels < depth {
+			return nil
+		}
+	}
+}
+
+// treeDepth returns the number of levels below the given block, checking
+// the tree against maxDepth as for Walk
+func treeDepth(block *Block, maxDepth int) (int, error) {
+	levels := 0
+	err := block.Walk(maxDepth, func(_ *Block, depth int) error {
+		levels = max(levels, depth)
+		return nil
+	})
+	return levels, err
+}
+
 // FetchBlocksMarkdown retrieves blocks as markdown
 func (c *Client) FetchBlocksMarkdown(id string, maxDepth int) (string, error) {
+	if err := c.checkSupported("markdown", func(caps *APICapabilities) bool { return caps.Markdown }); err != nil {
+		return "", err
+	}
+	markdown, _, err := c.fetchBlocksMarkdown(id, maxDepth)
+	return markdown, err
+}
+
+// fetchBlocksMarkdown is FetchBlocksMarkdown without the check against
+// Capabilities, also returning the response's Content-Type
+func (c *Client) fetchBlocksMarkdown(id string, maxDepth int) (string, string, error) {
 	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
 
 	params := url.Values{}
@@ -172,33 +552,87 @@ func (c *Client) FetchBlocksMarkdown(id string, maxDepth int) (string, error) {
 		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
 	}
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := c.newRequest("GET", reqURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return "", "", fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Accept", "text/markdown")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return "", fmt.Errorf("executing request: %w", err)
+		return "", "", fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return "", "", newAPIError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("reading response: %w", err)
+		return "", "", fmt.Errorf("reading response: %w", err)
 	}
 
-	return string(body), nil
+	return string(body), resp.Header.Get("Content-Type"), nil
 }
 
-// InsertBlocks adds new blocks to the document
+// InsertBlocks adds new blocks to the document. Nested Content is inserted
+// too: each parent is created first and its children are then inserted at
+// the end of it, so the returned blocks carry the IDs of the whole subtree.
+// With WithNormalizeHeadings, the headings of the request's markdown are
+// normalized first.
 func (c *Client) InsertBlocks(req InsertRequest) ([]Block, error) {
+	if c.normalizeHeadings && req.Markdown != "" {
+		req.Markdown = normalizeMarkdownHeadings(req.Markdown)
+	}
+	if !hasNestedContent(req.Blocks) {
+		return c.insertBlocks(req)
+	}
+
+	parents := req
+	parents.Blocks = make([]Block, len(req.Blocks))
+	for i, block := range req.Blocks {
+		block.Content = nil
+		parents.Blocks[i] = block
+	}
+
+	inserted, err := c.insertBlocks(parents)
+	if err != nil {
+		return nil, err
+	}
+	if len(inserted) != len(req.Blocks) {
+		return inserted, fmt.Errorf("expected %d inserted blocks, got %d", len(req.Blocks), len(inserted))
+	}
+
+	for i, block := range req.Blocks {
+		if len(block.Content) == 0 {
+			continue
+		}
+		children, err := c.InsertBlocks(InsertRequest{
+			Blocks:   block.Content,
+			Position: Position{Position: "end", PageID: inserted[i].ID},
+		})
+		if err != nil {
+			return inserted, fmt.Errorf("inserting children of %s: %w", inserted[i].ID, err)
+		}
+		inserted[i].Content = children
+	}
+
+	return inserted, nil
+}
+
+// hasNestedContent reports whether any of the blocks has children
+func hasNestedContent(blocks []Block) bool {
+	for _, block := range blocks {
+		if len(block.Content) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// insertBlocks issues a single insert request
+func (c *Client) insertBlocks(req InsertRequest) ([]Block, error) {
 	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -206,37 +640,37 @@ func (c *Client) InsertBlocks(req InsertRequest) ([]Block, error) {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("POST", reqURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	if err := c.decode(resp.Body, &itemsResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
 	var blocks []Block
-	if err := json.Unmarshal(itemsResp.Items, &blocks); err != nil {
+	if err := c.unmarshal(itemsResp.Items, &blocks); err != nil {
 		return nil, fmt.Errorf("unmarshaling blocks: %w", err)
 	}
 
 	return blocks, nil
 }
 
-// UpdateBlocks modifies existing blocks
+// UpdateBlocks modifies existing blocks. On a 207 partial success it returns
+// the updated blocks together with a *PartialFailureError.
 func (c *Client) UpdateBlocks(req UpdateRequest) ([]Block, error) {
 	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
 
@@ -245,37 +679,62 @@ func (c *Client) UpdateBlocks(req UpdateRequest) ([]Block, error) {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("PUT", reqURL, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("PUT", reqURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		return nil, newAPIError(resp)
 	}
 
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	if err := c.decode(resp.Body, &itemsResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusMultiStatus {
+		ids := make([]string, len(req.Blocks))
+		for i, block := range req.Blocks {
+			ids[i] = block.ID
+		}
+
+		succeeded, partial, err := splitItems(ids, itemsResp.Items)
+		if err != nil {
+			return nil, err
+		}
+
+		// Items carry per-item status fields next to the block, so they are
+		// decoded leniently even in strict mode
+		blocks := make([]Block, len(succeeded))
+		for i, raw := range succeeded {
+			if err := json.Unmarshal(raw, &blocks[i]); err != nil {
+				return nil, fmt.Errorf("unmarshaling block: %w", err)
+			}
+		}
+		if len(partial.Failed) > 0 {
+			return blocks, partial
+		}
+		return blocks, nil
+	}
+
 	var blocks []Block
-	if err := json.Unmarshal(itemsResp.Items, &blocks); err != nil {
+	if err := c.unmarshal(itemsResp.Items, &blocks); err != nil {
 		return nil, fmt.Errorf("unmarshaling blocks: %w", err)
 	}
 
 	return blocks, nil
 }
 
-// DeleteBlocks removes blocks from the document
+// DeleteBlocks removes blocks from the document. On a 207 partial success it
+// returns the deleted IDs together with a *PartialFailureError.
 func (c *Client) DeleteBlocks(blockIDs []string) ([]string, error) {
 	reqURL := fmt.Sprintf("%s/blocks", c.BaseURL)
 
@@ -285,32 +744,42 @@ func (c *Client) DeleteBlocks(blockIDs []string) ([]string, error) {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("DELETE", reqURL, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("DELETE", reqURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != 207 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		return nil, newAPIError(resp)
 	}
 
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	if err := c.decode(resp.Body, &itemsResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusMultiStatus {
+		_, partial, err := splitItems(blockIDs, itemsResp.Items)
+		if err != nil {
+			return nil, err
+		}
+		if len(partial.Failed) > 0 {
+			return partial.Succeeded, partial
+		}
+		return partial.Succeeded, nil
+	}
+
 	var deletedItems []struct {
 		ID string `json:"id"`
 	}
-	if err := json.Unmarshal(itemsResp.Items, &deletedItems); err != nil {
+	if err := c.unmarshal(itemsResp.Items, &deletedItems); err != nil {
 		return nil, fmt.Errorf("unmarshaling deleted IDs: %w", err)
 	}
 
@@ -322,7 +791,8 @@ func (c *Client) DeleteBlocks(blockIDs []string) ([]string, error) {
 	return ids, nil
 }
 
-// MoveBlocks repositions blocks in the document
+// MoveBlocks repositions blocks in the document. On a 207 partial success it
+// returns the moved IDs together with a *PartialFailureError.
 func (c *Client) MoveBlocks(req MoveRequest) ([]string, error) {
 	reqURL := fmt.Sprintf("%s/blocks/move", c.BaseURL)
 
@@ -331,32 +801,42 @@ func (c *Client) MoveBlocks(req MoveRequest) ([]string, error) {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("PUT", reqURL, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("PUT", reqURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != 207 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		return nil, newAPIError(resp)
 	}
 
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	if err := c.decode(resp.Body, &itemsResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusMultiStatus {
+		_, partial, err := splitItems(req.BlockIDs, itemsResp.Items)
+		if err != nil {
+			return nil, err
+		}
+		if len(partial.Failed) > 0 {
+			return partial.Succeeded, partial
+		}
+		return partial.Succeeded, nil
+	}
+
 	var movedItems []struct {
 		ID string `json:"id"`
 	}
-	if err := json.Unmarshal(itemsResp.Items, &movedItems); err != nil {
+	if err := c.unmarshal(itemsResp.Items, &movedItems); err != nil {
 		return nil, fmt.Errorf("unmarshaling moved IDs: %w", err)
 	}
 
@@ -370,6 +850,14 @@ func (c *Client) MoveBlocks(req MoveRequest) ([]string, error) {
 
 // Search finds blocks matching a pattern
 func (c *Client) Search(pattern string, caseSensitive bool, beforeCount, afterCount int) ([]SearchMatch, error) {
+	if err := c.checkSupported("search", func(caps *APICapabilities) bool { return caps.Search }); err != nil {
+		return nil, err
+	}
+	return c.search(pattern, caseSensitive, beforeCount, afterCount)
+}
+
+// search is Search without the check against Capabilities, for probing
+func (c *Client) search(pattern string, caseSensitive bool, beforeCount, afterCount int) ([]SearchMatch, error) {
 	reqURL := fmt.Sprintf("%s/blocks/search", c.BaseURL)
 
 	params := url.Values{}
@@ -386,39 +874,43 @@ func (c *Client) Search(pattern string, caseSensitive bool, beforeCount, afterCo
 
 	reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := c.newRequest("GET", reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 
 	var itemsResp ItemsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&itemsResp); err != nil {
+	if err := c.decode(resp.Body, &itemsResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
 	var matches []SearchMatch
-	if err := json.Unmarshal(itemsResp.Items, &matches); err != nil {
+	if err := c.unmarshal(itemsResp.Items, &matches); err != nil {
 		return nil, fmt.Errorf("unmarshaling search results: %w", err)
 	}
 
 	return matches, nil
 }
 
-// GenerateUploadURL creates a pre-signed S3 URL for file upload
+// GenerateUploadURL creates a pre-signed S3 URL for file upload. An empty
+// mimeType is inferred from the file name with DetectMimeType.
 func (c *Client) GenerateUploadURL(fileName, mimeType string) (*UploadLinkResponse, error) {
 	reqURL := fmt.Sprintf("%s/upload-link", c.BaseURL)
 
+	if mimeType == "" {
+		mimeType = DetectMimeType(fileName)
+	}
+
 	req := UploadLinkRequest{
 		FileName: fileName,
 		MimeType: mimeType,
@@ -428,25 +920,27 @@ func (c *Client) GenerateUploadURL(fileName, mimeType string) (*UploadLinkRespon
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest("POST", reqURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		err := newAPIError(resp)
+		c.capabilities.recordUpload(err)
+		return nil, err
 	}
+	c.capabilities.recordUpload(nil)
 
 	var uploadResp UploadLinkResponse
-	if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
+	if err := c.decode(resp.Body, &uploadResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
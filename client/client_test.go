@@ -0,0 +1,189 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestInsertBlocksNested(t *testing.T) {
+	f := NewFakeClient(Block{ID: "root", Content: []Block{{Type: "text", Markdown: "existing"}}})
+	c, _ := newFakeServer(t, f)
+
+	req := InsertRequest{
+		Blocks: []Block{
+			{Type: "page", Markdown: "Notes", Content: []Block{
+				{Type: "text", Markdown: "first"},
+				{Type: "text", Markdown: "second"},
+			}},
+			{Type: "text", Markdown: "after"},
+		},
+		Position: Position{Position: "end", PageID: "root"},
+	}
+	inserted, err := c.InsertBlocks(req)
+	if err != nil {
+		t.Fatalf("InsertBlocks: %v", err)
+	}
+
+	if len(inserted) != 2 {
+		t.Fatalf("got %d top-level blocks, want 2", len(inserted))
+	}
+	if got := len(inserted[0].Content); got != 2 {
+		t.Fatalf("got %d children of the page, want 2", got)
+	}
+
+	root, err := f.FetchBlocks("root", -1, false)
+	if err != nil {
+		t.Fatalf("FetchBlocks: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, block := range inserted {
+		for _, b := range block.Flatten() {
+			if b.ID == "" {
+				t.Errorf("block %q has no ID", b.Markdown)
+				continue
+			}
+			if seen[b.ID] {
+				t.Errorf("ID %s returned twice", b.ID)
+			}
+			seen[b.ID] = true
+			stored := root.FindByID(b.ID)
+			if stored == nil {
+				t.Errorf("returned ID %s is not in the document", b.ID)
+			} else if stored.Markdown != b.Markdown {
+				t.Errorf("block %s holds %q, want %q", b.ID, stored.Markdown, b.Markdown)
+			}
+		}
+	}
+
+	page := root.FindByID(inserted[0].ID)
+	if page == nil || len(page.Content) != 2 {
+		t.Fatalf("page children were not inserted under the page: %+v", page)
+	}
+	for i, child := range inserted[0].Content {
+		if page.Content[i].ID != child.ID {
+			t.Errorf("page child %d has ID %s, want %s", i, page.Content[i].ID, child.ID)
+		}
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	f := NewFakeClient(Block{ID: "root"})
+	c, _ := newFakeServer(t, f)
+
+	const workers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*2)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				_, err := c.InsertBlocks(InsertRequest{
+					Blocks:   []Block{{Type: "text", Markdown: fmt.Sprintf("worker %d line %d", w, i)}},
+					Position: Position{Position: "end", PageID: "root"},
+				})
+				if err != nil {
+					errs <- fmt.Errorf("insert: %w", err)
+				}
+				if _, err := c.FetchBlocks("root", 1, false); err != nil {
+					errs <- fmt.Errorf("fetch: %w", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	root, err := c.FetchBlocks("root", 1, false)
+	if err != nil {
+		t.Fatalf("FetchBlocks: %v", err)
+	}
+	if got := len(root.Content); got != workers*rounds {
+		t.Errorf("document has %d blocks, want %d", got, workers*rounds)
+	}
+}
+
+func TestIsAPIURL(t *testing.T) {
+	c := NewClient("https://connect.craft.do/links/abc/api/v1")
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://connect.craft.do/links/abc/api/v1", true},
+		{"https://connect.craft.do/links/abc/api/v1/blocks?id=1", true},
+		{"https://CONNECT.craft.do/links/abc/api/v1/blocks", true},
+		{"https://connect.craft.do/links/abc/api/v10/blocks", false},
+		{"https://connect.craft.do/links/abc/api/v1.evil/blocks", false},
+		{"https://connect.craft.do.evil.com/links/abc/api/v1/blocks", false},
+		{"https://connect.craft.do:8443/links/abc/api/v1/blocks", false},
+		{"http://connect.craft.do/links/abc/api/v1/blocks", false},
+		{"https://s3.amazonaws.com/bucket/links/abc/api/v1", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.isAPIURL(u); got != tt.want {
+			t.Errorf("isAPIURL(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestInsertRequestFromMarkdown(t *testing.T) {
+	tests := []struct {
+		id, position string
+		want         Position
+		wantErr      bool
+	}{
+		{id: "p", want: Position{Position: "end", PageID: "p"}},
+		{id: "p", position: "start", want: Position{Position: "start", PageID: "p"}},
+		{position: "end", want: Position{Position: "end"}},
+		{id: "s", position: "after", want: Position{Position: "after", SiblingID: "s"}},
+		{position: "before", wantErr: true},
+		{id: "p", position: "middle", wantErr: true},
+	}
+	for _, tt := range tests {
+		req, err := InsertRequestFromMarkdown(tt.id, "text", tt.position)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("InsertRequestFromMarkdown(%q, %q) error = %v, want error %v", tt.id, tt.position, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (req.Position != tt.want || req.Markdown != "text") {
+			t.Errorf("InsertRequestFromMarkdown(%q, %q) = %+v, want position %+v", tt.id, tt.position, req, tt.want)
+		}
+	}
+}
+
+func TestDefaultPosition(t *testing.T) {
+	tests := []struct {
+		pos     Position
+		want    Position
+		wantErr bool
+	}{
+		{want: Position{Position: "end", PageID: "page"}},
+		{pos: Position{Position: "start"}, want: Position{Position: "start", PageID: "page"}},
+		{pos: Position{Position: "start", PageID: "p"}, want: Position{Position: "start", PageID: "p"}},
+		{pos: Position{Position: "after", SiblingID: "s"}, want: Position{Position: "after", SiblingID: "s"}},
+		{pos: Position{Position: "start", SiblingID: "s"}, wantErr: true},
+		{pos: Position{Position: "after", PageID: "p"}, wantErr: true},
+		{pos: Position{Position: "after", PageID: "p", SiblingID: "s"}, wantErr: true},
+		{pos: Position{Position: "before"}, wantErr: true},
+		{pos: Position{Position: "middle"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := defaultPosition("page", tt.pos)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("defaultPosition(%+v) error = %v, want error %v", tt.pos, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("defaultPosition(%+v) = %+v, want %+v", tt.pos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseCSVTable builds a table block from CSV data, padding short records
+// with empty cells. With hasHeader the first record becomes the header with
+// its cells in bold; otherwise the header is numbered "Column 1", "Column
+// 2" and so on, since a table always leads with one.
+func ParseCSVTable(csvData string, hasHeader bool) (Block, error) {
+	r := csv.NewReader(strings.NewReader(csvData))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return Block{}, fmt.Errorf("parsing CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return Block{}, errors.New("CSV has no records")
+	}
+
+	columns := 0
+	for _, record := range records {
+		columns = max(columns, len(record))
+	}
+
+	header := make([]string, columns)
+	for i := range header {
+		header[i] = fmt.Sprintf("Column %d", i+1)
+	}
+	if hasHeader {
+		for i, name := range records[0] {
+			if name = strings.TrimSpace(name); name != "" {
+				name = "**" + name + "**"
+			}
+			header[i] = name
+		}
+		records = records[1:]
+	}
+
+	rows := [][]string{header}
+	for _, record := range records {
+		row := make([]string, columns)
+		copy(row, record)
+		rows = append(rows, row)
+	}
+	return NewTableBlock(rows), nil
+}
+
+// InsertCSVTable parses CSV data with ParseCSVTable and inserts the table.
+// An empty position means the end of pageID, and a start or end position
+// without a page ID targets pageID.
+func (c *Client) InsertCSVTable(pageID, csvData string, hasHeader bool, position Position) (*Block, error) {
+	table, err := ParseCSVTable(csvData, hasHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err = defaultPosition(pageID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{Blocks: []Block{table}, Position: position})
+	if err != nil {
+		return nil, fmt.Errorf("inserting table: %w", err)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.New("insert returned no blocks")
+	}
+	return &inserted[0], nil
+}
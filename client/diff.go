@@ -0,0 +1,124 @@
+package client
+
+import "fmt"
+
+// ApplyMarkdownDiff makes the direct children of a page match newMarkdown,
+// as split by ParseMarkdown, with as few changes as it can. Blocks whose
+// rendered markdown is unchanged keep their IDs and children; a changed
+// text block with the same styles is updated in place, and the rest are
+// deleted or inserted. Only the page's direct children are compared, so
+// nested content in newMarkdown is inserted as top-level blocks.
+func (c *Client) ApplyMarkdownDiff(pageID, newMarkdown string) error {
+	page, err := c.FetchBlocks(pageID, 1, false)
+	if err != nil {
+		return fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+	current := page.Content
+	wanted := ParseMarkdown(newMarkdown)
+
+	oldKeys := make([]string, len(current))
+	for i := range current {
+		oldKeys[i] = blockMarkdown(&current[i])
+	}
+	newKeys := make([]string, len(wanted))
+	for i := range wanted {
+		newKeys[i] = blockMarkdown(&wanted[i])
+	}
+
+	var (
+		updates []Block
+		deletes []string
+		inserts []InsertRequest
+	)
+
+	// anchor is the ID of the last block kept or updated, after which the
+	// next run of new blocks goes
+	anchor := ""
+	gap := func(olds, news []Block) {
+		for len(olds) > 0 && len(news) > 0 && updatable(&olds[0], &news[0]) {
+			updates = append(updates, Block{ID: olds[0].ID, Markdown: news[0].Markdown})
+			anchor = olds[0].ID
+			olds, news = olds[1:], news[1:]
+		}
+		for _, block := range olds {
+			deletes = append(deletes, block.ID)
+		}
+		if len(news) > 0 {
+			position := Position{Position: "start", PageID: page.ID}
+			if anchor != "" {
+				position = Position{Position: "after", SiblingID: anchor}
+			}
+			inserts = append(inserts, InsertRequest{Blocks: news, Position: position})
+		}
+	}
+
+	i, j := 0, 0
+	for _, pair := range longestCommonSubsequence(oldKeys, newKeys) {
+		gap(current[i:pair[0]], wanted[j:pair[1]])
+		anchor = current[pair[0]].ID
+		i, j = pair[0]+1, pair[1]+1
+	}
+	gap(current[i:], wanted[j:])
+
+	if len(updates) > 0 {
+		if _, err := c.UpdateBlocks(UpdateRequest{Blocks: updates}); err != nil {
+			return fmt.Errorf("updating blocks in page %s: %w", pageID, err)
+		}
+	}
+	if len(deletes) > 0 {
+		if _, err := c.DeleteBlocks(deletes); err != nil {
+			return fmt.Errorf("deleting blocks in page %s: %w", pageID, err)
+		}
+	}
+	for _, req := range inserts {
+		if _, err := c.InsertBlocks(req); err != nil {
+			return fmt.Errorf("inserting blocks in page %s: %w", pageID, err)
+		}
+	}
+	return nil
+}
+
+// updatable reports whether old can be turned into replacement by updating
+// its markdown alone
+func updatable(old, replacement *Block) bool {
+	textType := func(t string) bool { return t == "" || t == "text" }
+	return textType(old.Type) && textType(replacement.Type) &&
+		old.TextStyle == replacement.TextStyle &&
+		old.ListStyle == replacement.ListStyle &&
+		old.IndentationLevel == replacement.IndentationLevel &&
+		replacement.Markdown != ""
+}
+
+// longestCommonSubsequence returns the index pairs of a longest common
+// subsequence of a and b, in increasing order
+func longestCommonSubsequence(a, b []string) [][2]int {
+	// lengths[i][j] is the LCS length of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
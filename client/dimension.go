@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Dimension is an image size that is either a number of pixels or "auto".
+// The zero value means unset and is omitted from JSON.
+type Dimension int
+
+// autoDimension is the Dimension value for "auto"
+const autoDimension Dimension = -1
+
+// AutoWidth returns the "auto" dimension
+func AutoWidth() Dimension {
+	return autoDimension
+}
+
+// FixedWidth returns a dimension of px pixels. It panics if px is
+// negative, which would otherwise read as "auto" or an invalid size.
+func FixedWidth(px int) Dimension {
+	if px < 0 {
+		panic(fmt.Sprintf("client: FixedWidth with negative size %d", px))
+	}
+	return Dimension(px)
+}
+
+// IsAuto reports whether the dimension is "auto"
+func (d Dimension) IsAuto() bool {
+	return d == autoDimension
+}
+
+// Pixels returns the size in pixels, or 0 for "auto" and unset dimensions
+func (d Dimension) Pixels() int {
+	if d < 0 {
+		return 0
+	}
+	return int(d)
+}
+
+// String returns "auto" or the number of pixels
+func (d Dimension) String() string {
+	if d.IsAuto() {
+		return "auto"
+	}
+	return fmt.Sprint(int(d))
+}
+
+// MarshalJSON encodes the dimension as the string "auto" or a number.
+// Negative sizes other than "auto" are an error.
+func (d Dimension) MarshalJSON() ([]byte, error) {
+	if d.IsAuto() {
+		return []byte(`"auto"`), nil
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("invalid dimension %d", int(d))
+	}
+	return json.Marshal(int(d))
+}
+
+// UnmarshalJSON accepts the string "auto" or a whole number of pixels
+func (d *Dimension) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s != "auto" {
+			return fmt.Errorf("unrecognized dimension %q", s)
+		}
+		*d = autoDimension
+		return nil
+	}
+
+	var px float64
+	if err := json.Unmarshal(data, &px); err != nil {
+		return fmt.Errorf("unrecognized dimension %s", data)
+	}
+	if px < 0 || px != math.Trunc(px) || px > math.MaxInt32 {
+		return fmt.Errorf("invalid dimension %s", data)
+	}
+	*d = Dimension(px)
+	return nil
+}
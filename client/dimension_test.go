@@ -0,0 +1,33 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFixedWidth(t *testing.T) {
+	for _, px := range []int{0, 1, 640} {
+		d := FixedWidth(px)
+		if d.IsAuto() || d.Pixels() != px {
+			t.Errorf("FixedWidth(%d) = %v, want %d pixels", px, d, px)
+		}
+	}
+
+	for _, px := range []int{-1, -5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("FixedWidth(%d) did not panic", px)
+				}
+			}()
+			FixedWidth(px)
+		}()
+	}
+
+	if data, err := json.Marshal(Dimension(-5)); err == nil {
+		t.Errorf("json.Marshal(Dimension(-5)) = %s, want an error", data)
+	}
+	if data, err := json.Marshal(AutoWidth()); err != nil || string(data) != `"auto"` {
+		t.Errorf("json.Marshal(AutoWidth()) = %s, %v, want \"auto\"", data, err)
+	}
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"fmt"
+	"io"
+)
+
+// DownloadFile streams the file at rawURL, such as the URL of an image or
+// file block, to w and returns the number of bytes written. Redirects are
+// followed; any other non-2xx response is returned as an *APIError. The
+// client's MaxResponseBytes does not apply to downloads, and progress is
+// reported to the WithProgress callback.
+func (c *Client) DownloadFile(rawURL string, w io.Writer) (int64, error) {
+	req, err := c.newRequest("GET", rawURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return 0, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, newSentAPIError(resp)
+	}
+
+	n, err := io.Copy(w, withProgress(resp.Body, resp.ContentLength, c.progress))
+	if err != nil {
+		return n, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodyLength caps how much of a non-JSON error body is kept
+const maxErrorBodyLength = 200
+
+// maxErrorBodyRead caps how much of an error body is read from a response
+// that is not held to MaxResponseBytes
+const maxErrorBodyRead = 64 << 10
+
+// ErrNoMatch is returned when a search finds no matching block
+var ErrNoMatch = errors.New("no matching block")
+
+// ErrPreconditionFailed is returned when a block no longer holds the content
+// a conditional update expected
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrTreeTooDeep is returned when a block tree nests deeper than the
+// allowed maximum
+var ErrTreeTooDeep = errors.New("block tree too deep")
+
+// ErrTreeCycle is returned when a block ID occurs more than once in a tree,
+// which would make walks that follow IDs loop or revisit blocks
+var ErrTreeCycle = errors.New("block tree has a cycle")
+
+// APIError is returned when the API responds with an unexpected status
+type APIError struct {
+	StatusCode  int
+	ContentType string
+	Message     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API 404 response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// newAPIError builds an APIError from an error response. JSON bodies are
+// reduced to their message field; anything else, such as an HTML error page
+// from a proxy, is truncated and tagged with its content type.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, ContentType: mediaType}
+	if isJSONMediaType(mediaType) {
+		var payload struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(body, &payload); err == nil {
+			if payload.Message != "" {
+				apiErr.Message = payload.Message
+			} else {
+				apiErr.Message = payload.Error
+			}
+		}
+	}
+
+	if apiErr.Message == "" {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > maxErrorBodyLength {
+			msg = strings.ToValidUTF8(msg[:maxErrorBodyLength], "") + "..."
+		}
+		if mediaType != "" && !isJSONMediaType(mediaType) {
+			msg = fmt.Sprintf("%s (%s)", msg, mediaType)
+		}
+		apiErr.Message = msg
+	}
+
+	return apiErr
+}
+
+// newSentAPIError builds an APIError from an error response returned by
+// send, reading no more than maxErrorBodyRead bytes of its body
+func newSentAPIError(resp *http.Response) *APIError {
+	limited := *resp
+	limited.Body = io.NopCloser(io.LimitReader(resp.Body, maxErrorBodyRead))
+	return newAPIError(&limited)
+}
+
+// isJSONMediaType reports whether a media type carries JSON
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
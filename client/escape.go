@@ -0,0 +1,42 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+)
+
+// inlineEscaper backslash-escapes characters with markdown meaning anywhere
+// in a line
+var inlineEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`~`, `\~`,
+	`|`, `\|`,
+	`&`, `\&`,
+)
+
+// lineMarkerPattern matches the markers that only have meaning at the start
+// of a line: headings, setext heading underlines, quotes, list items and
+// dividers
+var lineMarkerPattern = regexp.MustCompile(`^(\s*)([#>+=-]|\d+[.)])`)
+
+// EscapeMarkdown escapes arbitrary text so it renders literally instead of
+// being read as markdown: characters with inline meaning, and the "&" of
+// entities, are backslash escaped anywhere, and heading, quote, list and
+// divider markers and setext underlines at the start of a line
+func EscapeMarkdown(s string) string {
+	lines := strings.Split(inlineEscaper.Replace(s), "\n")
+	for i, line := range lines {
+		if m := lineMarkerPattern.FindStringSubmatchIndex(line); m != nil {
+			// Escape the last character of the marker, e.g. "1\." or "\#"
+			end := m[5] - 1
+			lines[i] = line[:end] + `\` + line[end:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeMarkdownRoundTrip(t *testing.T) {
+	tests := []string{
+		"plain text",
+		"# not a heading",
+		"  ## indented heading",
+		"> not a quote",
+		"- not a list\n+ nor this\n* nor this",
+		"1. not numbered\n2) either",
+		"---",
+		"**bold** and _italic_ and ~~struck~~",
+		"`code` and ```fences```",
+		"[text](https://example.com) and ![alt](image.png)",
+		"footnote[^1]\n[^1]: definition",
+		"<page>tags</page> and a < b > c",
+		`back\slash \* and \\ twice`,
+		"a | table | row",
+		"task: - [ ] open",
+		"private use \ue02a\ue000 mixed with *stars* and \\",
+		"multi\nline\n\n# with\n> markers",
+		"setext\n===\nheadings\n  ---",
+		"&amp; &copy; &#169; and &#xA9; entities",
+	}
+	for _, text := range tests {
+		escaped := EscapeMarkdown(text)
+		if got := StripMarkdown(escaped); got != text {
+			t.Errorf("StripMarkdown(EscapeMarkdown(%q)) = %q (escaped %q)", text, got, escaped)
+		}
+		// Plain text drops the spaces around each block
+		if got := RenderPlainText(&Block{Type: "text", Markdown: escaped}); got != strings.TrimSpace(text) {
+			t.Errorf("RenderPlainText of escaped %q = %q", text, got)
+		}
+	}
+}
+
+func TestStripMarkdownPrivateUseRunes(t *testing.T) {
+	// Runes in the private use area must not be read as escaped characters
+	in := "\ue02a\\*\ue000 **x**"
+	if got, want := StripMarkdown(in), "\ue02a*\ue000 x"; got != want {
+		t.Errorf("StripMarkdown(%q) = %q, want %q", in, got, want)
+	}
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// FakeClient is an in-memory CraftAPI for tests. It holds a single document
+// tree and applies inserts, updates, deletes and moves to it the way the
+// Craft API does, including 404 errors and 207-style partial failures. It is
+// safe for concurrent use.
+type FakeClient struct {
+	mu     sync.Mutex
+	root   Block
+	nextID int
+}
+
+// NewFakeClient creates a fake holding a copy of the given document. Blocks
+// without an ID are assigned one, and the root defaults to a page with ID "0".
+func NewFakeClient(root Block) *FakeClient {
+	f := &FakeClient{root: cloneBlock(root)}
+	if f.root.ID == "" {
+		f.root.ID = "0"
+	}
+	if f.root.Type == "" {
+		f.root.Type = "page"
+	}
+	f.assignIDs(f.root.Content)
+	return f
+}
+
+// assignIDs gives every block in the tree without an ID a fresh one
+func (f *FakeClient) assignIDs(blocks []Block) {
+	for i := range blocks {
+		if blocks[i].ID == "" {
+			f.nextID++
+			blocks[i].ID = fmt.Sprintf("fake-%d", f.nextID)
+		}
+		f.assignIDs(blocks[i].Content)
+	}
+}
+
+// lookup returns the block with the given ID, treating "" as the root
+func (f *FakeClient) lookup(id string) *Block {
+	if id == "" || id == f.root.ID {
+		return &f.root
+	}
+	_, block := findWithParent(&f.root, id)
+	return block
+}
+
+// findWithParent searches below block for the given ID and returns the
+// match together with its parent, or nils if there is no match
+func findWithParent(block *Block, id string) (parent, found *Block) {
+	for i := range block.Content {
+		if block.Content[i].ID == id {
+			return block, &block.Content[i]
+		}
+		if p, b := findWithParent(&block.Content[i], id); b != nil {
+			return p, b
+		}
+	}
+	return nil, nil
+}
+
+// notFound builds the error the API returns for an unknown block
+func notFound(id string) error {
+	return &APIError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("block %s not found", id)}
+}
+
+// truncateDepth cuts a copied tree off below the given depth; a negative
+// depth keeps everything
+func truncateDepth(block *Block, depth int) {
+	if depth == 0 {
+		block.Content = nil
+		return
+	}
+	for i := range block.Content {
+		truncateDepth(&block.Content[i], depth-1)
+	}
+}
+
+// FetchBlocks returns a copy of the block with the given ID, limited to
+// maxDepth levels of children
+func (f *FakeClient) FetchBlocks(id string, maxDepth int, fetchMetadata bool) (*Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	block := f.lookup(id)
+	if block == nil {
+		return nil, notFound(id)
+	}
+
+	result := cloneBlock(*block)
+	truncateDepth(&result, maxDepth)
+	return &result, nil
+}
+
+// FetchBlocksMarkdown returns the block rendered with RenderMarkdown
+func (f *FakeClient) FetchBlocksMarkdown(id string, maxDepth int) (string, error) {
+	block, err := f.FetchBlocks(id, maxDepth, false)
+	if err != nil {
+		return "", err
+	}
+	return RenderMarkdown(block), nil
+}
+
+// target resolves a position to the parent block and the index in its
+// content at which new blocks go
+func (f *FakeClient) target(pos Position) (*Block, int, error) {
+	switch pos.Position {
+	case "start", "end":
+		page := f.lookup(pos.PageID)
+		if page == nil {
+			return nil, 0, notFound(pos.PageID)
+		}
+		if pos.Position == "start" {
+			return page, 0, nil
+		}
+		return page, len(page.Content), nil
+	case "before", "after":
+		parent, sibling := findWithParent(&f.root, pos.SiblingID)
+		if sibling == nil {
+			return nil, 0, notFound(pos.SiblingID)
+		}
+		index := slices.IndexFunc(parent.Content, func(b Block) bool { return b.ID == pos.SiblingID })
+		if pos.Position == "after" {
+			index++
+		}
+		return parent, index, nil
+	}
+	return nil, 0, &APIError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("invalid position %q", pos.Position)}
+}
+
+// InsertBlocks adds blocks, or a single text block holding the request's
+// markdown, at the requested position
+func (f *FakeClient) InsertBlocks(req InsertRequest) ([]Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	blocks := make([]Block, len(req.Blocks))
+	for i, block := range req.Blocks {
+		blocks[i] = copyWithoutIDs(block)
+	}
+	if req.Markdown != "" {
+		blocks = append(blocks, Block{Type: "text", Markdown: req.Markdown})
+	}
+	f.assignIDs(blocks)
+
+	parent, index, err := f.target(req.Position)
+	if err != nil {
+		return nil, err
+	}
+	parent.Content = slices.Insert(parent.Content, index, blocks...)
+
+	inserted := make([]Block, len(blocks))
+	for i, block := range blocks {
+		inserted[i] = cloneBlock(block)
+	}
+	return inserted, nil
+}
+
+// UpdateBlocks applies the non-empty fields of each block to the stored
+// block with the same ID
+func (f *FakeClient) UpdateBlocks(req UpdateRequest) ([]Block, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var updated []Block
+	partial := &PartialFailureError{}
+	for _, change := range req.Blocks {
+		block := f.lookup(change.ID)
+		if change.ID == "" || block == nil {
+			partial.Failed = append(partial.Failed, ItemFailure{ID: change.ID, Status: http.StatusNotFound, Message: "block not found"})
+			continue
+		}
+		applyUpdate(block, change)
+		updated = append(updated, cloneBlock(*block))
+		partial.Succeeded = append(partial.Succeeded, change.ID)
+	}
+
+	if len(partial.Failed) > 0 {
+		return updated, partial
+	}
+	return updated, nil
+}
+
+// applyUpdate copies the fields set on change onto block
+func applyUpdate(block *Block, change Block) {
+	if change.Markdown != "" {
+		block.Markdown = change.Markdown
+	}
+	if change.TextStyle != "" {
+		block.TextStyle = change.TextStyle
+	}
+	if change.IndentationLevel != 0 {
+		block.IndentationLevel = change.IndentationLevel
+	}
+	if change.ListStyle != "" {
+		block.ListStyle = change.ListStyle
+	}
+	if change.ListStart != 0 {
+		block.ListStart = change.ListStart
+	}
+	if change.Font != "" {
+		block.Font = change.Font
+	}
+	if change.Color != "" {
+		block.Color = change.Color
+	}
+	if change.URL != "" {
+		block.URL = change.URL
+	}
+	if change.AltText != "" {
+		block.AltText = change.AltText
+	}
+	if change.Width != 0 {
+		block.Width = change.Width
+	}
+	if change.Height != 0 {
+		block.Height = change.Height
+	}
+	if change.FileName != "" {
+		block.FileName = change.FileName
+	}
+	if change.Language != "" {
+		block.Language = change.Language
+	}
+	if change.Rows != nil {
+		block.Rows = cloneBlock(change).Rows
+	}
+	if change.Collapsed != nil {
+		collapsed := *change.Collapsed
+		block.Collapsed = &collapsed
+	}
+}
+
+// remove detaches the block with the given ID from the tree and returns it
+func (f *FakeClient) remove(id string) (Block, bool) {
+	parent, block := findWithParent(&f.root, id)
+	if block == nil {
+		return Block{}, false
+	}
+	removed := *block
+	parent.Content = slices.DeleteFunc(parent.Content, func(b Block) bool { return b.ID == id })
+	return removed, true
+}
+
+// DeleteBlocks removes the blocks with the given IDs and their subtrees
+func (f *FakeClient) DeleteBlocks(blockIDs []string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	partial := &PartialFailureError{Succeeded: []string{}}
+	for _, id := range blockIDs {
+		if _, ok := f.remove(id); !ok {
+			partial.Failed = append(partial.Failed, ItemFailure{ID: id, Status: http.StatusNotFound, Message: "block not found"})
+			continue
+		}
+		partial.Succeeded = append(partial.Succeeded, id)
+	}
+
+	if len(partial.Failed) > 0 {
+		return partial.Succeeded, partial
+	}
+	return partial.Succeeded, nil
+}
+
+// DeleteSubtree removes a block with its descendants and returns their IDs,
+// children before parents
+func (f *FakeClient) DeleteSubtree(id string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	block, ok := f.remove(id)
+	if !ok {
+		return nil, notFound(id)
+	}
+	return subtreeIDs(&block, 0)
+}
+
+// MoveBlocks detaches the given blocks and reinserts them, in request order,
+// at the requested position
+func (f *FakeClient) MoveBlocks(req MoveRequest) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Resolve the position before detaching anything so a bad request
+	// leaves the tree untouched
+	if _, _, err := f.target(req.Position); err != nil {
+		return nil, err
+	}
+	if slices.Contains(req.BlockIDs, req.Position.SiblingID) || slices.Contains(req.BlockIDs, req.Position.PageID) {
+		return nil, &APIError{StatusCode: http.StatusBadRequest, Message: "cannot move blocks relative to themselves"}
+	}
+
+	partial := &PartialFailureError{Succeeded: []string{}}
+	var moving []Block
+	for _, id := range req.BlockIDs {
+		block, ok := f.remove(id)
+		if !ok {
+			partial.Failed = append(partial.Failed, ItemFailure{ID: id, Status: http.StatusNotFound, Message: "block not found"})
+			continue
+		}
+		moving = append(moving, block)
+		partial.Succeeded = append(partial.Succeeded, id)
+	}
+
+	parent, index, err := f.target(req.Position)
+	if err != nil {
+		// The target was inside one of the moved subtrees
+		return nil, err
+	}
+	parent.Content = slices.Insert(parent.Content, index, moving...)
+
+	if len(partial.Failed) > 0 {
+		return partial.Succeeded, partial
+	}
+	return partial.Succeeded, nil
+}
+
+// Search matches the pattern against the markdown of every block below the
+// root, in document order, with sibling blocks as context
+func (f *FakeClient) Search(pattern string, caseSensitive bool, beforeCount, afterCount int) ([]SearchMatch, error) {
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &APIError{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("invalid pattern: %v", err)}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	matches := []SearchMatch{}
+	path := []PagePathElement{{ID: f.root.ID, Content: PageTitle(&f.root)}}
+	searchBlocks(&f.root, path, re, beforeCount, afterCount, &matches)
+	return matches, nil
+}
+
+// searchBlocks appends matches among the descendants of parent
+func searchBlocks(parent *Block, path []PagePathElement, re *regexp.Regexp, beforeCount, afterCount int, matches *[]SearchMatch) {
+	for i := range parent.Content {
+		block := &parent.Content[i]
+		if re.MatchString(block.Markdown) {
+			*matches = append(*matches, SearchMatch{
+				BlockID:       block.ID,
+				Markdown:      block.Markdown,
+				PageBlockPath: slices.Clone(path),
+				BeforeBlocks:  contextBlocks(parent.Content[max(0, i-beforeCount):i]),
+				AfterBlocks:   contextBlocks(parent.Content[i+1 : min(len(parent.Content), i+1+afterCount)]),
+			})
+		}
+		if len(block.Content) > 0 {
+			childPath := append(slices.Clip(path), PagePathElement{ID: block.ID, Content: PageTitle(block)})
+			searchBlocks(block, childPath, re, beforeCount, afterCount, matches)
+		}
+	}
+}
+
+// contextBlocks converts blocks to search context entries
+func contextBlocks(blocks []Block) []ContextBlock {
+	context := make([]ContextBlock, len(blocks))
+	for i, block := range blocks {
+		context[i] = ContextBlock{BlockID: block.ID, Markdown: block.Markdown}
+	}
+	return context
+}
+
+// GenerateUploadURL returns placeholder upload and raw URLs for the file
+func (f *FakeClient) GenerateUploadURL(fileName, mimeType string) (*UploadLinkResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	return &UploadLinkResponse{
+		UploadURL: fmt.Sprintf("https://upload.invalid/%d/%s", f.nextID, fileName),
+		RawURL:    fmt.Sprintf("https://files.invalid/%d/%s", f.nextID, fileName),
+	}, nil
+}
+
+// Ping always succeeds, the fake has nothing to reach
+func (f *FakeClient) Ping() error {
+	return nil
+}
+
+// RootPageID returns the ID of the fake's root page
+func (f *FakeClient) RootPageID() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.root.ID, nil
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// fakeDoc is a page with three paragraphs and a nested page
+func fakeDoc() Block {
+	return Block{ID: "root", Type: "page", Markdown: "Doc", Content: []Block{
+		{ID: "a", Type: "text", Markdown: "alpha"},
+		{ID: "b", Type: "text", Markdown: "beta"},
+		{ID: "sub", Type: "page", Markdown: "Sub", Content: []Block{
+			{ID: "c", Type: "text", Markdown: "gamma"},
+		}},
+	}}
+}
+
+// contentIDs returns the IDs of the children of the stored block id
+func contentIDs(t *testing.T, f *FakeClient, id string) []string {
+	t.Helper()
+	block, err := f.FetchBlocks(id, 1, false)
+	if err != nil {
+		t.Fatalf("FetchBlocks(%q): %v", id, err)
+	}
+	var ids []string
+	for _, child := range block.Content {
+		ids = append(ids, child.ID)
+	}
+	return ids
+}
+
+// wantStatus fails the test unless err is an APIError with the given status
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+		t.Errorf("err = %v, want an API error with status %d", err, status)
+	}
+}
+
+func TestFakeInsert(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	inserted, err := c.InsertBlocks(InsertRequest{Markdown: "delta", Position: Position{Position: "after", SiblingID: "a"}})
+	if err != nil {
+		t.Fatalf("InsertBlocks: %v", err)
+	}
+	if len(inserted) != 1 || inserted[0].ID == "" || inserted[0].Markdown != "delta" {
+		t.Fatalf("InsertBlocks = %+v, want one block with an ID", inserted)
+	}
+	if got, want := contentIDs(t, f, "root"), []string{"a", inserted[0].ID, "b", "sub"}; !slices.Equal(got, want) {
+		t.Errorf("root content = %v, want %v", got, want)
+	}
+
+	inserted, err = c.InsertBlocks(InsertRequest{Blocks: []Block{{Type: "text", Markdown: "first"}}, Position: Position{Position: "start", PageID: "sub"}})
+	if err != nil {
+		t.Fatalf("InsertBlocks: %v", err)
+	}
+	if got, want := contentIDs(t, f, "sub"), []string{inserted[0].ID, "c"}; !slices.Equal(got, want) {
+		t.Errorf("sub content = %v, want %v", got, want)
+	}
+
+	_, err = c.InsertBlocks(InsertRequest{Markdown: "lost", Position: Position{Position: "end", PageID: "missing"}})
+	wantStatus(t, err, http.StatusNotFound)
+}
+
+func TestFakeUpdate(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	updated, err := c.UpdateBlocks(UpdateRequest{Blocks: []Block{{ID: "c", Markdown: "GAMMA"}}})
+	if err != nil {
+		t.Fatalf("UpdateBlocks: %v", err)
+	}
+	if len(updated) != 1 || updated[0].ID != "c" || updated[0].Markdown != "GAMMA" {
+		t.Errorf("UpdateBlocks = %+v, want block c with the new markdown", updated)
+	}
+	if block, _ := f.FetchBlocks("c", 0, false); block.Markdown != "GAMMA" || block.Type != "text" {
+		t.Errorf("stored block c = %+v, want the new markdown and its type kept", block)
+	}
+}
+
+func TestFakeDelete(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	deleted, err := c.DeleteBlocks([]string{"b", "sub"})
+	if err != nil {
+		t.Fatalf("DeleteBlocks: %v", err)
+	}
+	if want := []string{"b", "sub"}; !slices.Equal(deleted, want) {
+		t.Errorf("DeleteBlocks = %v, want %v", deleted, want)
+	}
+	if got, want := contentIDs(t, f, "root"), []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("root content = %v, want %v", got, want)
+	}
+
+	_, err = c.FetchBlocks("c", 0, false)
+	wantStatus(t, err, http.StatusNotFound)
+}
+
+func TestFakeMove(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	moved, err := c.MoveBlocks(MoveRequest{BlockIDs: []string{"c", "a"}, Position: Position{Position: "after", SiblingID: "b"}})
+	if err != nil {
+		t.Fatalf("MoveBlocks: %v", err)
+	}
+	if want := []string{"c", "a"}; !slices.Equal(moved, want) {
+		t.Errorf("MoveBlocks = %v, want %v", moved, want)
+	}
+	if got, want := contentIDs(t, f, "root"), []string{"b", "c", "a", "sub"}; !slices.Equal(got, want) {
+		t.Errorf("root content = %v, want %v", got, want)
+	}
+	if got := contentIDs(t, f, "sub"); len(got) != 0 {
+		t.Errorf("sub content = %v, want none", got)
+	}
+
+	_, err = c.MoveBlocks(MoveRequest{BlockIDs: []string{"b"}, Position: Position{Position: "after", SiblingID: "b"}})
+	wantStatus(t, err, http.StatusBadRequest)
+}
+
+func TestFakeSearch(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	matches, err := c.Search("^(alpha|gamma)$", true, 1, 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(matches) != 2 || matches[0].BlockID != "a" || matches[1].BlockID != "c" {
+		t.Fatalf("Search = %+v, want blocks a and c", matches)
+	}
+	if after := matches[0].AfterBlocks; len(after) != 1 || after[0].BlockID != "b" {
+		t.Errorf("context after a = %+v, want block b", after)
+	}
+
+	if matches, err := c.Search("ALPHA", true, 0, 0); err != nil || len(matches) != 0 {
+		t.Errorf("case-sensitive Search = %+v, %v, want no matches", matches, err)
+	}
+	_, err = c.Search("(", true, 0, 0)
+	wantStatus(t, err, http.StatusBadRequest)
+}
+
+func TestFakePartialFailure(t *testing.T) {
+	f := NewFakeClient(fakeDoc())
+	c, _ := newFakeServer(t, f)
+
+	// wantPartial checks the succeeded and failed IDs of a partial failure
+	wantPartial := func(op string, err error, succeeded, failed []string) {
+		t.Helper()
+		var partial *PartialFailureError
+		if !errors.As(err, &partial) {
+			t.Fatalf("%s: err = %v, want a PartialFailureError", op, err)
+		}
+		var failedIDs []string
+		for _, failure := range partial.Failed {
+			failedIDs = append(failedIDs, failure.ID)
+			if failure.Status != http.StatusNotFound {
+				t.Errorf("%s: failure %+v, want status 404", op, failure)
+			}
+		}
+		if !slices.Equal(partial.Succeeded, succeeded) || !slices.Equal(failedIDs, failed) {
+			t.Errorf("%s: succeeded %v, failed %v, want %v and %v", op, partial.Succeeded, failedIDs, succeeded, failed)
+		}
+	}
+
+	updated, err := c.UpdateBlocks(UpdateRequest{Blocks: []Block{{ID: "a", Markdown: "ALPHA"}, {ID: "x", Markdown: "lost"}}})
+	wantPartial("UpdateBlocks", err, []string{"a"}, []string{"x"})
+	if len(updated) != 1 || updated[0].Markdown != "ALPHA" {
+		t.Errorf("UpdateBlocks = %+v, want the updated block a", updated)
+	}
+
+	moved, err := c.MoveBlocks(MoveRequest{BlockIDs: []string{"y", "b"}, Position: Position{Position: "end", PageID: "sub"}})
+	wantPartial("MoveBlocks", err, []string{"b"}, []string{"y"})
+	if !slices.Equal(moved, []string{"b"}) {
+		t.Errorf("MoveBlocks = %v, want [b]", moved)
+	}
+	if got, want := contentIDs(t, f, "sub"), []string{"c", "b"}; !slices.Equal(got, want) {
+		t.Errorf("sub content = %v, want %v", got, want)
+	}
+
+	deleted, err := c.DeleteBlocks([]string{"c", "z"})
+	wantPartial("DeleteBlocks", err, []string{"c"}, []string{"z"})
+	if !slices.Equal(deleted, []string{"c"}) {
+		t.Errorf("DeleteBlocks = %v, want [c]", deleted)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newFakeServer serves the blocks endpoints of the Craft API from f and
+// returns a client pointed at it. Partial failures of f are answered with a
+// 207 listing the succeeded items followed by the failed ones.
+func newFakeServer(t *testing.T, f *FakeClient) (*Client, *httptest.Server) {
+	t.Helper()
+
+	writeErr := func(w http.ResponseWriter, err error) {
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(apiErr.StatusCode)
+		json.NewEncoder(w).Encode(map[string]string{"message": apiErr.Message})
+	}
+	writeItems := func(w http.ResponseWriter, v any) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(ItemsResponse{Items: data})
+	}
+	idItems := func(ids []string) []any {
+		items := make([]any, len(ids))
+		for i, id := range ids {
+			items[i] = map[string]string{"id": id}
+		}
+		return items
+	}
+	// writeResult writes the items of a successful or partly failed request
+	writeResult := func(w http.ResponseWriter, items []any, err error) {
+		var partial *PartialFailureError
+		if !errors.As(err, &partial) {
+			if err != nil {
+				writeErr(w, err)
+				return
+			}
+			writeItems(w, items)
+			return
+		}
+		for _, failure := range partial.Failed {
+			items = append(items, multiStatusItem{ID: failure.ID, Status: failure.Status, Message: failure.Message})
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMultiStatus)
+		json.NewEncoder(w).Encode(ItemsResponse{Items: data})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /blocks", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		depth := -1
+		if v := q.Get("maxDepth"); v != "" {
+			depth, _ = strconv.Atoi(v)
+		}
+		if r.Header.Get("Accept") == "text/markdown" {
+			md, err := f.FetchBlocksMarkdown(q.Get("id"), depth)
+			if err != nil {
+				writeErr(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "text/markdown")
+			w.Write([]byte(md))
+			return
+		}
+		block, err := f.FetchBlocks(q.Get("id"), depth, false)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(block)
+	})
+	mux.HandleFunc("POST /blocks", func(w http.ResponseWriter, r *http.Request) {
+		var req InsertRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		blocks, err := f.InsertBlocks(req)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeItems(w, blocks)
+	})
+	mux.HandleFunc("PUT /blocks", func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		blocks, err := f.UpdateBlocks(req)
+		items := make([]any, len(blocks))
+		for i, block := range blocks {
+			items[i] = block
+		}
+		writeResult(w, items, err)
+	})
+	mux.HandleFunc("DELETE /blocks", func(w http.ResponseWriter, r *http.Request) {
+		var req DeleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids, err := f.DeleteBlocks(req.BlockIDs)
+		writeResult(w, idItems(ids), err)
+	})
+	mux.HandleFunc("PUT /blocks/move", func(w http.ResponseWriter, r *http.Request) {
+		var req MoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ids, err := f.MoveBlocks(req)
+		writeResult(w, idItems(ids), err)
+	})
+
+	mux.HandleFunc("GET /blocks/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		before, _ := strconv.Atoi(q.Get("beforeBlockCount"))
+		after, _ := strconv.Atoi(q.Get("afterBlockCount"))
+		matches, err := f.Search(q.Get("pattern"), q.Get("caseSensitive") == "true", before, after)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeItems(w, matches)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL), srv
+}
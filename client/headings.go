@@ -0,0 +1,87 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// headingNormalizer assigns heading levels that never skip one, keeping
+// each heading's nesting under the closest preceding heading of a lower
+// original level
+type headingNormalizer struct {
+	// stack holds the original and assigned levels of the open headings
+	stack []struct{ original, assigned int }
+}
+
+// level returns the level to use for a heading originally at the given one
+func (n *headingNormalizer) level(original int) int {
+	for len(n.stack) > 0 && n.stack[len(n.stack)-1].original >= original {
+		n.stack = n.stack[:len(n.stack)-1]
+	}
+	assigned := 1
+	if len(n.stack) > 0 {
+		assigned = n.stack[len(n.stack)-1].assigned + 1
+	}
+	n.stack = append(n.stack, struct{ original, assigned int }{original, assigned})
+	return assigned
+}
+
+// NormalizeHeadings returns a copy of the blocks with heading levels
+// rewritten so that, in document order, the first heading is an h1 and no
+// heading is more than one level below the one it falls under: h1, h4, h4,
+// h2 becomes h1, h2, h2, h2. Both the TextStyle and any leading "#"
+// markers in the markdown are updated.
+func NormalizeHeadings(blocks []Block) []Block {
+	normalized := make([]Block, len(blocks))
+	for i, block := range blocks {
+		normalized[i] = cloneBlock(block)
+	}
+
+	var n headingNormalizer
+	for i := range normalized {
+		for _, block := range normalized[i].Flatten() {
+			original := headingLevel(block)
+			if original == 0 {
+				continue
+			}
+			level := n.level(original)
+			block.TextStyle = fmt.Sprintf("h%d", level)
+			if m := headingPattern.FindStringSubmatchIndex(block.Markdown); m != nil {
+				block.Markdown = strings.Repeat("#", level) + block.Markdown[m[3]:]
+			}
+		}
+	}
+	return normalized
+}
+
+// normalizeMarkdownHeadings applies NormalizeHeadings to the ATX headings
+// of markdown in place, keeping their indentation and leaving everything
+// else untouched, including code fenced with ``` or ~~~ and code indented
+// four or more spaces
+func normalizeMarkdownHeadings(md string) string {
+	var n headingNormalizer
+	lines := strings.Split(md, "\n")
+	fence := ""
+	for i, line := range lines {
+		text := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(text)]
+
+		if fence != "" {
+			if closesFence(text, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if len(strings.ReplaceAll(indent, "\t", "    ")) >= 4 {
+			continue
+		}
+		if fence = openingFence(text); fence != "" {
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatchIndex(text); m != nil {
+			lines[i] = indent + strings.Repeat("#", n.level(m[3]-m[2])) + text[m[3]:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,55 @@
+package client
+
+import "testing"
+
+func TestNormalizeHeadings(t *testing.T) {
+	blocks := []Block{
+		{Type: "text", TextStyle: "h1", Markdown: "# Title"},
+		{Type: "text", TextStyle: "h4", Markdown: "#### Deep"},
+		{Type: "page", Markdown: "Page", Content: []Block{
+			{Type: "text", TextStyle: "h4", Markdown: "#### Nested"},
+		}},
+		{Type: "text", Markdown: "text"},
+		{Type: "text", TextStyle: "h2", Markdown: "## Section"},
+	}
+	normalized := NormalizeHeadings(blocks)
+
+	want := []struct{ style, markdown string }{
+		{"h1", "# Title"},
+		{"h2", "## Deep"},
+		{"h2", "## Nested"},
+		{"", "text"},
+		{"h2", "## Section"},
+	}
+	got := []*Block{&normalized[0], &normalized[1], &normalized[2].Content[0], &normalized[3], &normalized[4]}
+	for i, w := range want {
+		if got[i].TextStyle != w.style || got[i].Markdown != w.markdown {
+			t.Errorf("block %d = {%s %q}, want {%s %q}", i, got[i].TextStyle, got[i].Markdown, w.style, w.markdown)
+		}
+	}
+	if blocks[1].Markdown != "#### Deep" {
+		t.Errorf("input was modified: %q", blocks[1].Markdown)
+	}
+}
+
+func TestNormalizeMarkdownHeadings(t *testing.T) {
+	tests := []struct {
+		name, md, want string
+	}{
+		{"skipped levels", "# A\n#### B\n## C", "# A\n## B\n## C"},
+		{"starts deep", "### A\n##### B", "# A\n## B"},
+		{"keeps indentation", "# A\n  #### B\n   ### C", "# A\n  ## B\n   ## C"},
+		{"indented code", "# A\n    #### code\n\t#### code\n### B", "# A\n    #### code\n\t#### code\n## B"},
+		{"backtick fence", "# A\n```\n### code\n```\n### B", "# A\n```\n### code\n```\n## B"},
+		{"tilde fence", "# A\n~~~ sh\n### code\n```\n### still code\n~~~\n### B", "# A\n~~~ sh\n### code\n```\n### still code\n~~~\n## B"},
+		{"long fence", "# A\n````\n```\n### code\n```\n````\n### B", "# A\n````\n```\n### code\n```\n````\n## B"},
+		{"not a heading", "#hashtag\n### A", "#hashtag\n# A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeMarkdownHeadings(tt.md); got != tt.want {
+				t.Errorf("normalizeMarkdownHeadings(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
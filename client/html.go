@@ -0,0 +1,285 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// lineSyntaxPattern matches the structural tags and line-level markers
+	// whose meaning RenderHTML expresses with elements instead
+	lineSyntaxPattern = regexp.MustCompile(`(?m)</?(?:page|card|callout)>|^\s*(?:#{1,6}|>|[-*+]|\d+\.)\s+`)
+
+	// taskPattern matches a task checkbox at the start of a list item
+	taskPattern = regexp.MustCompile(`^\[([ xX])\]\s+`)
+
+	// codeSpanPattern matches an inline code span
+	codeSpanPattern = regexp.MustCompile("`([^`]+)`")
+
+	// inlineHTML holds the replacements from escaped inline markdown to
+	// HTML, applied in order outside code spans
+	inlineHTML = []struct {
+		pattern     *regexp.Regexp
+		replacement func(m []string) string
+	}{
+		{regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`), func(m []string) string {
+			if !safeURL(m[2]) {
+				return m[1]
+			}
+			return fmt.Sprintf(`<img src="%s" alt="%s">`, m[2], m[1])
+		}},
+		{regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`), func(m []string) string {
+			if !safeURL(m[2]) {
+				return m[1]
+			}
+			return fmt.Sprintf(`<a href="%s">%s</a>`, m[2], m[1])
+		}},
+		{regexp.MustCompile(`\*\*(.+?)\*\*`), func(m []string) string { return "<strong>" + m[1] + "</strong>" }},
+		{regexp.MustCompile(`\*(.+?)\*`), func(m []string) string { return "<em>" + m[1] + "</em>" }},
+		{regexp.MustCompile(`~~(.+?)~~`), func(m []string) string { return "<del>" + m[1] + "</del>" }},
+	}
+)
+
+// RenderHTML converts a block tree to semantic HTML: pages become sections,
+// heading styles h1-h6, list items ul/ol, code blocks pre/code, and so on.
+// All text is escaped, and links with schemes other than http, https,
+// mailto and craftdocs are rendered as plain text.
+func RenderHTML(root *Block) (string, error) {
+	if root == nil {
+		return "", errors.New("nil root block")
+	}
+
+	var b strings.Builder
+	renderHTMLBlocks([]Block{*root}, 1, &b)
+	return b.String(), nil
+}
+
+// htmlList is a list element left open while its items are rendered
+type htmlList struct {
+	tag   string
+	level int
+}
+
+// renderHTMLBlocks renders a run of sibling blocks, grouping consecutive
+// list items into lists nested by indentation level
+func renderHTMLBlocks(blocks []Block, depth int, b *strings.Builder) {
+	var lists []htmlList
+	closeLists := func(level int) {
+		for len(lists) > 0 && lists[len(lists)-1].level > level {
+			b.WriteString("</li></" + lists[len(lists)-1].tag + ">\n")
+			lists = lists[:len(lists)-1]
+		}
+	}
+
+	for i := range blocks {
+		block := &blocks[i]
+		tag := listTag(block)
+		if tag == "" {
+			closeLists(-1)
+			renderHTMLBlock(block, depth, b)
+			continue
+		}
+
+		level := block.IndentationLevel
+		closeLists(level)
+		if n := len(lists); n > 0 && lists[n-1].level == level && lists[n-1].tag != tag {
+			closeLists(level - 1)
+		}
+		if n := len(lists); n > 0 && lists[n-1].level == level {
+			b.WriteString("</li>\n")
+		} else {
+			if tag == "ol" && block.ListStart > 1 {
+				fmt.Fprintf(b, "<ol start=\"%d\">\n", block.ListStart)
+			} else {
+				b.WriteString("<" + tag + ">\n")
+			}
+			lists = append(lists, htmlList{tag: tag, level: level})
+		}
+
+		// The item stays open so deeper items nest inside it
+		b.WriteString("<li>" + listItemHTML(block.Markdown))
+		if len(block.Content) > 0 {
+			b.WriteString("\n")
+			renderHTMLBlocks(block.Content, depth, b)
+		}
+	}
+	closeLists(-1)
+}
+
+// listTag returns "ul" or "ol" for a list item block, or "" for any other
+func listTag(block *Block) string {
+	if block.Type != "" && block.Type != "text" {
+		return ""
+	}
+	switch {
+	case block.ListStyle == "numbered":
+		return "ol"
+	case block.ListStyle != "" && block.ListStyle != "none":
+		return "ul"
+	}
+	if m := listItemPattern.FindStringSubmatch(block.Markdown); m != nil {
+		if strings.HasSuffix(m[2], ".") {
+			return "ol"
+		}
+		return "ul"
+	}
+	return ""
+}
+
+// listItemHTML renders the text of a list item, turning a leading task
+// marker into a disabled checkbox
+func listItemHTML(md string) string {
+	text := lineSyntaxPattern.ReplaceAllString(md, "")
+	m := taskPattern.FindStringSubmatch(text)
+	if m == nil {
+		return inlineToHTML(text)
+	}
+
+	checkbox := `<input type="checkbox" disabled>`
+	if m[1] != " " {
+		checkbox = `<input type="checkbox" checked disabled>`
+	}
+	return checkbox + " " + inlineToHTML(text[len(m[0]):])
+}
+
+// renderHTMLBlock renders a single non-list block followed by its children
+func renderHTMLBlock(block *Block, depth int, b *strings.Builder) {
+	switch {
+	case block.Type == "page":
+		level := min(depth, 6)
+		fmt.Fprintf(b, "<section>\n<h%d>%s</h%d>\n", level, inlineToHTML(PageTitle(block)), level)
+		renderHTMLBlocks(block.Content, depth+1, b)
+		b.WriteString("</section>\n")
+		return
+
+	case block.Type == "toggle":
+		open := " open"
+		if block.Collapsed != nil && *block.Collapsed {
+			open = ""
+		}
+		fmt.Fprintf(b, "<details%s>\n<summary>%s</summary>\n", open, blockTextHTML(block.Markdown))
+		renderHTMLBlocks(block.Content, depth, b)
+		b.WriteString("</details>\n")
+		return
+
+	case block.Type == "code":
+		class := ""
+		if block.Language != "" {
+			class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(block.Language))
+		}
+		fmt.Fprintf(b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(block.Markdown))
+
+	case block.Type == "table":
+		b.WriteString(renderHTMLTable(block.Rows))
+
+	case block.Type == "divider":
+		b.WriteString("<hr>\n")
+
+	case block.Type == "image":
+		if safeURL(block.URL) {
+			fmt.Fprintf(b, "<img src=\"%s\" alt=\"%s\">\n", html.EscapeString(block.URL), html.EscapeString(block.AltText))
+		}
+
+	case block.Type == "video" || block.Type == "file":
+		if safeURL(block.URL) {
+			name := block.FileName
+			if name == "" {
+				name = block.URL
+			}
+			fmt.Fprintf(b, "<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(block.URL), html.EscapeString(name))
+		}
+
+	case strings.TrimSpace(block.Markdown) == "":
+
+	case headingLevel(block) > 0:
+		fmt.Fprintf(b, "<%s>%s</%s>\n", block.TextStyle, blockTextHTML(block.Markdown), block.TextStyle)
+
+	case block.TextStyle == "quote":
+		fmt.Fprintf(b, "<blockquote><p>%s</p></blockquote>\n", blockTextHTML(block.Markdown))
+
+	case block.TextStyle == "callout":
+		fmt.Fprintf(b, "<aside><p>%s</p></aside>\n", blockTextHTML(block.Markdown))
+
+	default:
+		fmt.Fprintf(b, "<p>%s</p>\n", blockTextHTML(block.Markdown))
+	}
+
+	renderHTMLBlocks(block.Content, depth, b)
+}
+
+// renderHTMLTable renders rows as a table with the first row as the header
+func renderHTMLTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<table>\n<thead>\n")
+	for i, row := range rows {
+		cell := "td"
+		if i == 0 {
+			cell = "th"
+		}
+		b.WriteString("<tr>")
+		for _, text := range row {
+			fmt.Fprintf(&b, "<%s>%s</%s>", cell, inlineToHTML(text), cell)
+		}
+		b.WriteString("</tr>\n")
+		if i == 0 {
+			b.WriteString("</thead>\n<tbody>\n")
+		}
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return b.String()
+}
+
+// blockTextHTML renders the markdown of a text block without its line-level
+// markers, keeping line breaks
+func blockTextHTML(md string) string {
+	text := inlineToHTML(lineSyntaxPattern.ReplaceAllString(md, ""))
+	return strings.ReplaceAll(text, "\n", "<br>\n")
+}
+
+// inlineToHTML escapes text and converts its inline markdown to HTML,
+// leaving the contents of code spans untouched
+func inlineToHTML(md string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range codeSpanPattern.FindAllStringSubmatchIndex(md, -1) {
+		b.WriteString(inlineSpanHTML(md[last:m[0]]))
+		b.WriteString("<code>" + html.EscapeString(md[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	b.WriteString(inlineSpanHTML(md[last:]))
+	return b.String()
+}
+
+// inlineSpanHTML escapes text without code spans and converts its links and
+// emphasis
+func inlineSpanHTML(md string) string {
+	text := html.EscapeString(md)
+	for _, syntax := range inlineHTML {
+		text = syntax.pattern.ReplaceAllStringFunc(text, func(s string) string {
+			return syntax.replacement(syntax.pattern.FindStringSubmatch(s))
+		})
+	}
+	return text
+}
+
+// safeURL reports whether a URL may be used as a link or image source. The
+// URL may already be HTML-escaped.
+func safeURL(raw string) bool {
+	u, err := url.Parse(html.UnescapeString(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto", "craftdocs":
+		return raw != ""
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// NewMarker returns a random UUID suitable for InsertBlocksOnce
+func NewMarker() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating marker: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// markerComment is the hidden text embedded in a block to carry a marker
+func markerComment(marker string) string {
+	return fmt.Sprintf("<!-- marker:%s -->", marker)
+}
+
+// FindByMarker returns the block carrying the given marker, or nil if no
+// block in the document carries it
+func (c *Client) FindByMarker(marker string) (*Block, error) {
+	matches, err := c.Search(regexp.QuoteMeta(markerComment(marker)), true, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("searching for marker: %w", err)
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	return c.FetchBlocks(matches[0].BlockID, 0, false)
+}
+
+// InsertBlocksOnce inserts blocks tagged with a client-generated marker,
+// skipping the insert if a block with that marker already exists. Retrying
+// with the same marker is therefore safe. When the insert is skipped the
+// previously inserted block carrying the marker is returned. The marker
+// goes at the end of the markdown, or of the first text block among
+// req.Blocks; without a text block it is inserted as a text block of its
+// own ahead of the others, so images, tables and code are never altered.
+func (c *Client) InsertBlocksOnce(marker string, req InsertRequest) ([]Block, error) {
+	if marker == "" {
+		return nil, errors.New("marker is required")
+	}
+
+	existing, err := c.FindByMarker(marker)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return []Block{*existing}, nil
+	}
+
+	comment := markerComment(marker)
+	switch {
+	case req.Markdown != "":
+		req.Markdown = req.Markdown + " " + comment
+	case len(req.Blocks) > 0:
+		i := slices.IndexFunc(req.Blocks, func(b Block) bool { return b.Type == "" || b.Type == "text" })
+		if i < 0 {
+			req.Blocks = slices.Insert(slices.Clone(req.Blocks), 0, Block{Type: "text", Markdown: comment})
+			break
+		}
+		blocks := slices.Clone(req.Blocks)
+		blocks[i].Markdown = blocks[i].Markdown + " " + comment
+		req.Blocks = blocks
+	default:
+		return nil, errors.New("nothing to insert")
+	}
+
+	return c.InsertBlocks(req)
+}
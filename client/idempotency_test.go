@@ -0,0 +1,69 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsertBlocksOnce(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []Block
+		// tagged is the index among the inserted blocks of the one
+		// carrying the marker
+		tagged int
+		count  int
+	}{
+		{
+			name:   "text block",
+			blocks: []Block{{Type: "code", Markdown: "x := 1", Language: "go"}, {Type: "text", Markdown: "note"}},
+			tagged: 1,
+			count:  2,
+		},
+		{
+			name:   "no text block",
+			blocks: []Block{{Type: "code", Markdown: "x := 1", Language: "go"}, {Type: "image", URL: "https://example.com/a.png"}},
+			tagged: 0,
+			count:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeClient(Block{ID: "root", Type: "page"})
+			c, _ := newFakeServer(t, f)
+			marker, err := NewMarker()
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := InsertRequest{Blocks: tt.blocks, Position: Position{Position: "end", PageID: "root"}}
+
+			inserted, err := c.InsertBlocksOnce(marker, req)
+			if err != nil {
+				t.Fatalf("InsertBlocksOnce: %v", err)
+			}
+			if len(inserted) != tt.count {
+				t.Fatalf("inserted %d blocks, want %d", len(inserted), tt.count)
+			}
+			for i, block := range inserted {
+				if tagged := strings.Contains(block.Markdown, markerComment(marker)); tagged != (i == tt.tagged) {
+					t.Errorf("block %d (%s) %q: carries marker = %v, want %v", i, block.Type, block.Markdown, tagged, i == tt.tagged)
+				}
+			}
+			if tt.blocks[0].Markdown != "x := 1" {
+				t.Errorf("request blocks were modified: %+v", tt.blocks)
+			}
+
+			again, err := c.InsertBlocksOnce(marker, req)
+			if err != nil {
+				t.Fatalf("InsertBlocksOnce again: %v", err)
+			}
+			if len(again) != 1 || again[0].ID != inserted[tt.tagged].ID {
+				t.Errorf("retry returned %+v, want the tagged block %s", again, inserted[tt.tagged].ID)
+			}
+			root, _ := f.FetchBlocks("root", -1, false)
+			if len(root.Content) != tt.count {
+				t.Errorf("document holds %d blocks after retry, want %d", len(root.Content), tt.count)
+			}
+		})
+	}
+}
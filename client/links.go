@@ -0,0 +1,133 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// markdownLinkPattern matches inline markdown links and images, capturing
+// the image marker, the text and the destination. Brackets in the text may
+// be backslash-escaped, as BlockMention writes them.
+var markdownLinkPattern = regexp.MustCompile(`(!?)\[((?:\\.|[^\]\\])*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+
+var (
+	// linkIDPattern matches a valid Craft connect link ID
+	linkIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	// linkSegmentPattern matches the link ID segment of a connect base URL
+	linkSegmentPattern = regexp.MustCompile(`/links/[^/]+`)
+)
+
+// ForLink returns a client for another document behind the given connect
+// link ID. It shares the receiver's HTTP client and settings, so it is
+// cheap to create per call.
+func (c *Client) ForLink(linkID string) (*Client, error) {
+	if !linkIDPattern.MatchString(linkID) {
+		return nil, fmt.Errorf("invalid link ID %q", linkID)
+	}
+	if !linkSegmentPattern.MatchString(c.BaseURL) {
+		return nil, fmt.Errorf("base URL %q has no link segment", c.BaseURL)
+	}
+
+	linked := *c
+	linked.BaseURL = linkSegmentPattern.ReplaceAllLiteralString(c.BaseURL, "/links/"+linkID)
+	// The other document has its own root and is probed separately
+	linked.root = &rootCache{}
+	linked.capabilities = &capabilityCache{}
+	return &linked, nil
+}
+
+// FetchBlocksFrom retrieves blocks from the document behind another connect
+// link
+func (c *Client) FetchBlocksFrom(linkID, id string, maxDepth int, fetchMetadata bool) (*Block, error) {
+	linked, err := c.ForLink(linkID)
+	if err != nil {
+		return nil, err
+	}
+	return linked.FetchBlocks(id, maxDepth, fetchMetadata)
+}
+
+// blockIDFromURL extracts the referenced block ID from a Craft block URL,
+// either a craftdocs:// deep link or a craft.do web link, both of which
+// carry it in a blockId or id query parameter
+func blockIDFromURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing link %q: %w", rawURL, err)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if u.Scheme != "craftdocs" && host != "craft.do" && !strings.HasSuffix(host, ".craft.do") {
+		return "", fmt.Errorf("not a Craft link: %q", rawURL)
+	}
+
+	if id := queryBlockID(u); id != "" {
+		return id, nil
+	}
+	return "", fmt.Errorf("link has no block ID: %q", rawURL)
+}
+
+// queryBlockID returns the block ID in a URL's blockId or id parameter
+func queryBlockID(u *url.URL) string {
+	query := u.Query()
+	for _, key := range []string{"blockId", "id"} {
+		if id := query.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// ParseBlockURL extracts the connect link ID and block ID from a
+// connect.craft.do URL such as
+// https://connect.craft.do/links/<linkID>/api/v1/blocks?id=<blockID>. The
+// block ID comes from a blockId or id parameter and is empty for a URL
+// that names only the document, standing for its root.
+func ParseBlockURL(rawURL string) (linkID, blockID string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing link %q: %w", rawURL, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || !strings.EqualFold(u.Hostname(), "connect.craft.do") {
+		return "", "", fmt.Errorf("not a Craft connect link: %q", rawURL)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != "links" || !linkIDPattern.MatchString(segments[1]) {
+		return "", "", fmt.Errorf("link has no valid link ID: %q", rawURL)
+	}
+	return segments[1], queryBlockID(u), nil
+}
+
+// ResolveLink fetches the block referenced by a Craft block link
+func (c *Client) ResolveLink(rawURL string) (*Block, error) {
+	id, err := blockIDFromURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchBlocks(id, 0, false)
+}
+
+// ExtractLinks returns the distinct link targets in the tree, in document
+// order: the URL field of every block and the destinations of inline
+// markdown links and images
+func ExtractLinks(root *Block) []string {
+	links := []string{}
+	seen := make(map[string]bool)
+	add := func(link string) {
+		if link != "" && !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+
+	for _, block := range root.Flatten() {
+		add(block.URL)
+		for _, m := range markdownLinkPattern.FindAllStringSubmatch(block.Markdown, -1) {
+			add(m[3])
+		}
+	}
+	return links
+}
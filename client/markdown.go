@@ -0,0 +1,394 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RenderMarkdown converts a block tree to markdown, separating blocks with
+// blank lines and rendering each page's content after its title
+func RenderMarkdown(root *Block) string {
+	var parts []string
+	renderMarkdown(root, &parts)
+	return strings.Join(parts, "\n\n")
+}
+
+// RenderPlainText converts a block tree to plain text for indexing: the
+// text of each block with markdown syntax stripped, separated by blank
+// lines, keeping line breaks within blocks. Code is kept verbatim and table
+// rows become lines of space-separated cells.
+func RenderPlainText(root *Block) string {
+	var parts []string
+	for _, block := range root.Flatten() {
+		if text := strings.TrimSpace(blockText(block)); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// renderMarkdown appends the markdown of a block and its descendants
+func renderMarkdown(block *Block, parts *[]string) {
+	// Toggles become HTML details elements wrapping their children, open
+	// unless explicitly collapsed
+	if block.Type == "toggle" {
+		open := " open"
+		if block.Collapsed != nil && *block.Collapsed {
+			open = ""
+		}
+		*parts = append(*parts, fmt.Sprintf("<details%s>\n<summary>%s</summary>", open, block.Markdown))
+		for i := range block.Content {
+			renderMarkdown(&block.Content[i], parts)
+		}
+		*parts = append(*parts, "</details>")
+		return
+	}
+
+	if md := blockMarkdown(block); md != "" {
+		*parts = append(*parts, md)
+	}
+	for i := range block.Content {
+		renderMarkdown(&block.Content[i], parts)
+	}
+}
+
+// codeFence returns a backtick fence for code: three backticks, or one more
+// than the longest run of backticks in the code so the fence cannot close
+// early
+func codeFence(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// openingFence returns the fence of three or more backticks or tildes that
+// opens fenced code on text, a line without its indentation, or "" if text
+// does not open one
+func openingFence(text string) string {
+	if !strings.HasPrefix(text, "```") && !strings.HasPrefix(text, "~~~") {
+		return ""
+	}
+	fence := text[:len(text)-len(strings.TrimLeft(text, text[:1]))]
+	// The info string after a backtick fence cannot hold backticks
+	if fence[0] == '`' && strings.Contains(text[len(fence):], "`") {
+		return ""
+	}
+	return fence
+}
+
+// closesFence reports whether text, a line without its indentation, closes
+// code opened with fence: the fence's character at least as many times and
+// nothing else
+func closesFence(text, fence string) bool {
+	text = strings.TrimRight(text, " \t")
+	return len(text) >= len(fence) && strings.Trim(text, fence[:1]) == ""
+}
+
+// blockMarkdown renders a single block without its children
+func blockMarkdown(block *Block) string {
+	switch block.Type {
+	case "code":
+		fence := codeFence(block.Markdown)
+		return fmt.Sprintf("%s%s\n%s\n%s", fence, block.Language, block.Markdown, fence)
+	case "table":
+		return renderTable(block.Rows)
+	case "divider":
+		return "---"
+	case "image":
+		if block.URL != "" {
+			return fmt.Sprintf("![%s](%s)", block.AltText, block.URL)
+		}
+	case "video", "file":
+		if block.URL != "" {
+			name := block.FileName
+			if name == "" {
+				name = block.URL
+			}
+			return fmt.Sprintf("[%s](%s)", name, block.URL)
+		}
+	}
+
+	// Quotes and callouts both render as block quotes
+	if (block.TextStyle == "quote" || block.TextStyle == "callout") && block.Markdown != "" {
+		return "> " + strings.ReplaceAll(block.Markdown, "\n", "\n> ")
+	}
+
+	md := block.Markdown
+	if block.ListStyle == "numbered" && block.ListStart > 0 && md != "" {
+		md = numberListItem(md, block.ListStart)
+	}
+
+	if block.IndentationLevel > 0 && md != "" {
+		// List items indent four spaces per level, past the widest common
+		// marker, so an item nested under "1." is not read as a sibling
+		indent := "  "
+		if listItemPattern.MatchString(md) {
+			indent = "    "
+		}
+		return strings.Repeat(indent, block.IndentationLevel) + md
+	}
+	return md
+}
+
+// numberListItem gives a list item the marker "n.", replacing the marker it
+// has or adding one if it has none
+func numberListItem(md string, n int) string {
+	if m := listItemPattern.FindStringSubmatchIndex(md); m != nil {
+		return md[:m[4]] + fmt.Sprintf("%d.", n) + md[m[5]:]
+	}
+	return fmt.Sprintf("%d. %s", n, md)
+}
+
+// renderTable renders rows as a pipe table, treating the first row as the
+// header and padding short rows with empty cells
+func renderTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		cells := make([]string, columns)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// dividerPattern matches a horizontal rule such as ---, *** or _ _ _
+	dividerPattern = regexp.MustCompile(`^(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+	// headingPattern matches an ATX heading and captures its level markers
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s`)
+
+	// tableSeparatorPattern matches the line between a table header and body
+	tableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	// listItemPattern matches a bullet or numbered list item, capturing the
+	// leading indentation and the marker
+	listItemPattern = regexp.MustCompile(`^(\s*)([-*+]|\d+\.)\s`)
+
+	// footnotePattern matches the first line of a footnote definition such
+	// as [^1]: text
+	footnotePattern = regexp.MustCompile(`^\[\^[^\]\s]+\]:`)
+)
+
+// ParseMarkdown splits markdown into blocks: fenced code becomes a code
+// block, pipe tables become table blocks, horizontal rules become dividers,
+// headings and list items each get their own block, and remaining text is
+// split into paragraphs at blank lines. A list item is indented one level
+// below the nearest less indented item above it. Each footnote definition
+// becomes a text block holding the definition verbatim, including its
+// indented continuation lines, while references like [^1] stay in the
+// text. It is the inverse of RenderMarkdown for the blocks it supports.
+func ParseMarkdown(md string) []Block {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	blocks := []Block{}
+	var paragraph []string
+
+	// listIndents holds the indentation widths of the enclosing list items,
+	// outermost first, so a nested item is one level deeper than its parent
+	// whether the list indents by two spaces, four or a tab
+	var listIndents []int
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, Block{Type: "text", Markdown: strings.Join(paragraph, "\n")})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !listItemPattern.MatchString(line) {
+			listIndents = nil
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case openingFence(trimmed) != "":
+			flush()
+			fence := openingFence(trimmed)
+			language := strings.TrimSpace(trimmed[len(fence):])
+			var code []string
+			for i++; i < len(lines) && !closesFence(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, NewCodeBlock(strings.Join(code, "\n"), language))
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSeparatorPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			rows := [][]string{parseTableRow(trimmed)}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, parseTableRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			blocks = append(blocks, NewTableBlock(rows))
+
+		case dividerPattern.MatchString(trimmed):
+			flush()
+			blocks = append(blocks, NewDividerBlock())
+
+		case footnotePattern.MatchString(line):
+			flush()
+			definition := []string{line}
+			for i+1 < len(lines) && footnoteContinues(lines[i+1:]) {
+				i++
+				definition = append(definition, lines[i])
+			}
+			blocks = append(blocks, Block{Type: "text", Markdown: strings.TrimRight(strings.Join(definition, "\n"), "\n")})
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			level := len(headingPattern.FindStringSubmatch(trimmed)[1])
+			blocks = append(blocks, Block{Type: "text", TextStyle: fmt.Sprintf("h%d", level), Markdown: trimmed})
+
+		case listItemPattern.MatchString(line):
+			flush()
+			m := listItemPattern.FindStringSubmatch(line)
+			listStyle := "bullet"
+			if strings.HasSuffix(m[2], ".") {
+				listStyle = "numbered"
+			}
+
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			for len(listIndents) > 0 && listIndents[len(listIndents)-1] > indent {
+				listIndents = listIndents[:len(listIndents)-1]
+			}
+			if n := len(listIndents); n == 0 || listIndents[n-1] < indent {
+				listIndents = append(listIndents, indent)
+			}
+
+			blocks = append(blocks, Block{
+				Type:             "text",
+				Markdown:         trimmed,
+				ListStyle:        listStyle,
+				IndentationLevel: len(listIndents) - 1,
+			})
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// footnoteContinues reports whether the next of the remaining lines belongs
+// to the footnote definition before it: an indented line, or a blank line
+// followed by one
+func footnoteContinues(rest []string) bool {
+	indented := func(line string) bool {
+		return strings.TrimSpace(line) != "" && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"))
+	}
+	if strings.TrimSpace(rest[0]) == "" {
+		return len(rest) > 1 && indented(rest[1])
+	}
+	return indented(rest[0])
+}
+
+// parseTableRow splits a pipe table row into cells, honoring escaped pipes
+func parseTableRow(line string) []string {
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = strings.TrimSuffix(line, "|")
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// markdownSyntax holds the replacements that reduce markdown to its text,
+// applied in order
+var markdownSyntax = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`</?[a-zA-Z][^>]*>`), ""},                           // structural tags like <page>
+	{regexp.MustCompile(`(?m)^\s*#{1,6}\s+`), ""},                           // heading markers
+	{regexp.MustCompile(`(?m)^\s*>\s?`), ""},                                // block quotes
+	{regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+\.)\s+(?:\[[ xX]\]\s+)?`), ""}, // list and task markers
+	{regexp.MustCompile(`(?m)^\[\^[^\]\s]+\]:\s*`), ""},                     // footnote definition labels
+	{regexp.MustCompile(`\[\^[^\]\s]+\]`), ""},                              // footnote references
+	{regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`), "$1"},                   // links and images
+	{regexp.MustCompile("\\*\\*|__|~~|[*`]"), ""},                           // emphasis and code spans
+}
+
+// escapedCharPattern matches a backslash escape of ASCII punctuation, such
+// as those written by EscapeMarkdown
+var escapedCharPattern = regexp.MustCompile("\\\\[!-/:-@\\[-`{-~]")
+
+// StripMarkdown reduces markdown to its text, removing structural tags,
+// line markers, emphasis and link syntax while keeping line breaks.
+// Backslash-escaped characters are kept literally, without the backslash.
+func StripMarkdown(md string) string {
+	// Escaped characters stand in as runes that do not occur in md while
+	// the syntax is stripped, so neither can be mistaken for the other
+	placeholders := make(map[byte]rune)
+	originals := make(map[rune]byte)
+	next := rune(0xE000) // Start of the private use area
+	source := md
+	md = escapedCharPattern.ReplaceAllStringFunc(md, func(s string) string {
+		r, ok := placeholders[s[1]]
+		if !ok {
+			for strings.ContainsRune(source, next) {
+				next++
+			}
+			r = next
+			next++
+			placeholders[s[1]], originals[r] = r, s[1]
+		}
+		return string(r)
+	})
+	for _, syntax := range markdownSyntax {
+		md = syntax.pattern.ReplaceAllString(md, syntax.replacement)
+	}
+	if len(originals) == 0 {
+		return md
+	}
+	return strings.Map(func(r rune) rune {
+		if c, ok := originals[r]; ok {
+			return rune(c)
+		}
+		return r
+	}, md)
+}
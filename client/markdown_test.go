@@ -0,0 +1,172 @@
+package client
+
+import "testing"
+
+// nestedList mixes ordered and unordered items over three levels, indented
+// two spaces per level
+const nestedList = `- fruit
+  1. apples
+     - green
+     - red
+  2. pears
+- vegetables
+  * carrots
+    1. orange
+    2. purple
+10. last`
+
+type listItem struct {
+	markdown string
+	style    string
+	level    int
+}
+
+var nestedListItems = []listItem{
+	{"- fruit", "bullet", 0},
+	{"1. apples", "numbered", 1},
+	{"- green", "bullet", 2},
+	{"- red", "bullet", 2},
+	{"2. pears", "numbered", 1},
+	{"- vegetables", "bullet", 0},
+	{"* carrots", "bullet", 1},
+	{"1. orange", "numbered", 2},
+	{"2. purple", "numbered", 2},
+	{"10. last", "numbered", 0},
+}
+
+func checkListItems(t *testing.T, blocks []Block, want []listItem) {
+	t.Helper()
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d: %+v", len(blocks), len(want), blocks)
+	}
+	for i, w := range want {
+		b := blocks[i]
+		if b.Markdown != w.markdown || b.ListStyle != w.style || b.IndentationLevel != w.level {
+			t.Errorf("block %d = {%q %s %d}, want {%q %s %d}",
+				i, b.Markdown, b.ListStyle, b.IndentationLevel, w.markdown, w.style, w.level)
+		}
+	}
+}
+
+func TestParseMarkdownNestedLists(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+	}{
+		{"two spaces", nestedList},
+		{"tabs", "- fruit\n\t1. apples\n\t\t- green\n\t\t- red\n\t2. pears\n- vegetables\n\t* carrots\n\t\t1. orange\n\t\t2. purple\n10. last"},
+		{"four spaces", "- fruit\n    1. apples\n        - green\n        - red\n    2. pears\n- vegetables\n    * carrots\n        1. orange\n        2. purple\n10. last"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkListItems(t, ParseMarkdown(tt.md), nestedListItems)
+		})
+	}
+}
+
+func TestNestedListRoundTrip(t *testing.T) {
+	page := &Block{Type: "page", Content: ParseMarkdown(nestedList)}
+	rendered := RenderMarkdown(page)
+
+	// Nested items indent four spaces per level, so an item under "10." is
+	// not mistaken for a sibling
+	want := `- fruit
+
+    1. apples
+
+        - green
+
+        - red
+
+    2. pears
+
+- vegetables
+
+    * carrots
+
+        1. orange
+
+        2. purple
+
+10. last`
+	if rendered != want {
+		t.Fatalf("RenderMarkdown =\n%s\nwant\n%s", rendered, want)
+	}
+
+	checkListItems(t, ParseMarkdown(rendered), nestedListItems)
+	if again := RenderMarkdown(&Block{Type: "page", Content: ParseMarkdown(rendered)}); again != rendered {
+		t.Errorf("second render differs:\n%s", again)
+	}
+}
+
+func TestRenderMarkdownIndentation(t *testing.T) {
+	tests := []struct {
+		name  string
+		block Block
+		want  string
+	}{
+		{"list item", Block{Markdown: "- item", ListStyle: "bullet", IndentationLevel: 2}, "        - item"},
+		{"numbered item", Block{Markdown: "3. item", ListStyle: "numbered", IndentationLevel: 1}, "    3. item"},
+		{"plain text", Block{Markdown: "text", IndentationLevel: 2}, "    text"},
+		{"top level", Block{Markdown: "- item", ListStyle: "bullet"}, "- item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockMarkdown(&tt.block); got != tt.want {
+				t.Errorf("blockMarkdown = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCodeFence(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"x := 1", "```go\nx := 1\n```"},
+		{"use `x`", "```go\nuse `x`\n```"},
+		{"```\nnested\n```", "````go\n```\nnested\n```\n````"},
+		{"a ``` b ````` c", "``````go\na ``` b ````` c\n``````"},
+	}
+	for _, tt := range tests {
+		block := NewCodeBlock(tt.code, "go")
+		if got := blockMarkdown(&block); got != tt.want {
+			t.Errorf("blockMarkdown(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseMarkdownFences(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       string
+		code     string
+		language string
+	}{
+		{"backticks", "```go\nx := 1\n```", "x := 1", "go"},
+		{"tildes", "~~~python\nprint(1)\n```\n~~~", "print(1)\n```", "python"},
+		{"longer closing fence", "~~~\na\n~~~~~", "a", ""},
+		{"long backtick fence", "````md\n```\nnested\n```\n````", "```\nnested\n```", "md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := ParseMarkdown(tt.md + "\n\nafter")
+			if len(blocks) != 2 {
+				t.Fatalf("ParseMarkdown returned %d blocks, want 2: %+v", len(blocks), blocks)
+			}
+			if got := blocks[0]; got.Type != "code" || got.Markdown != tt.code || got.Language != tt.language {
+				t.Errorf("code block = %+v, want %q in %q", got, tt.code, tt.language)
+			}
+			if blocks[1].Markdown != "after" {
+				t.Errorf("block after the code = %+v", blocks[1])
+			}
+		})
+	}
+
+	// Rendered code survives a round trip whatever fences it holds
+	code := NewCodeBlock("~~~\n```\n````", "")
+	if blocks := ParseMarkdown(blockMarkdown(&code)); len(blocks) != 1 || blocks[0].Markdown != code.Markdown {
+		t.Errorf("round trip of %q gave %+v", code.Markdown, blocks)
+	}
+}
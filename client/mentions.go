@@ -0,0 +1,87 @@
+package client
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Mention kinds
+const (
+	MentionBlock = "block" // an inline link to a Craft block or page
+	MentionUser  = "user"  // an @-mention
+)
+
+// Mention is an inline reference found in block markdown
+type Mention struct {
+	Kind    string // MentionBlock or MentionUser
+	Text    string // the link text, or the name after @
+	BlockID string // the referenced block, for MentionBlock
+	URL     string // the link destination, for MentionBlock
+}
+
+var (
+	// userMentionPattern matches an @-mention that is not part of a word or
+	// an email address
+	userMentionPattern = regexp.MustCompile(`(^|[^\w@.])@([\w][\w.-]*\w|\w)`)
+
+	// bracketEscaper and bracketUnescaper escape and unescape the brackets
+	// in link text
+	bracketEscaper   = strings.NewReplacer(`[`, `\[`, `]`, `\]`)
+	bracketUnescaper = strings.NewReplacer(`\[`, `[`, `\]`, `]`)
+)
+
+// ExtractMentions returns the mentions in markdown in order: links to Craft
+// blocks, recognized as by ResolveLink, and @-mentions of people
+func ExtractMentions(markdown string) []Mention {
+	type found struct {
+		at      int
+		mention Mention
+	}
+	var all []found
+
+	for _, m := range markdownLinkPattern.FindAllStringSubmatchIndex(markdown, -1) {
+		if m[3] > m[2] {
+			continue // an image
+		}
+		link := markdown[m[6]:m[7]]
+		id, err := blockIDFromURL(link)
+		if err != nil {
+			continue
+		}
+		all = append(all, found{m[4], Mention{Kind: MentionBlock, Text: bracketUnescaper.Replace(markdown[m[4]:m[5]]), BlockID: id, URL: link}})
+	}
+
+	// Mentions inside link text or destinations are not separate mentions
+	masked := markdownLinkPattern.ReplaceAllStringFunc(markdown, func(s string) string {
+		return strings.Repeat(" ", len(s))
+	})
+	for _, m := range userMentionPattern.FindAllStringSubmatchIndex(masked, -1) {
+		all = append(all, found{m[4], Mention{Kind: MentionUser, Text: masked[m[4]:m[5]]}})
+	}
+
+	slices.SortFunc(all, func(a, b found) int { return a.at - b.at })
+	mentions := make([]Mention, len(all))
+	for i, f := range all {
+		mentions[i] = f.mention
+	}
+	return mentions
+}
+
+// BlockMention returns markdown linking to a block, shown as text. The
+// space ID is optional but needed for the link to open in the Craft app.
+func BlockMention(text, blockID, spaceID string) string {
+	params := url.Values{}
+	params.Set("blockId", blockID)
+	if spaceID != "" {
+		params.Set("spaceId", spaceID)
+	}
+	text = bracketEscaper.Replace(text)
+	return "[" + text + "](craftdocs://open?" + params.Encode() + ")"
+}
+
+// UserMention returns the @-mention markdown for a name
+func UserMention(name string) string {
+	return "@" + name
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	link := BlockMention("Notes [draft]", "B1", "S1")
+	md := "Ask @ada about " + link + `, see [spec](craftdocs://open?blockId=B2 "Spec") ` +
+		"but not ![img](craftdocs://open?blockId=B3), [@grace](https://example.com) or bob@example.com"
+
+	want := []Mention{
+		{Kind: MentionUser, Text: "ada"},
+		{Kind: MentionBlock, Text: "Notes [draft]", BlockID: "B1", URL: "craftdocs://open?blockId=B1&spaceId=S1"},
+		{Kind: MentionBlock, Text: "spec", BlockID: "B2", URL: "craftdocs://open?blockId=B2"},
+	}
+	if got := ExtractMentions(md); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractMentions(%q) =\n%+v\nwant\n%+v", md, got, want)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	root := &Block{Content: []Block{
+		{Type: "image", URL: "https://example.com/a.png"},
+		{Markdown: `[a](https://a.example) ![b](<https://b.example/b.png> "B") [c \] d](https://c.example)`},
+		{Markdown: "[again](https://a.example)"},
+	}}
+	want := []string{"https://example.com/a.png", "https://a.example", "https://b.example/b.png", "https://c.example"}
+	if got := ExtractLinks(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractLinks = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ItemFailure describes a single block an operation could not be applied to
+type ItemFailure struct {
+	ID      string
+	Status  int // the item's HTTP status, or 0 if the response gave none
+	Message string
+}
+
+// PartialFailureError is returned together with the successful results when
+// the API responds with 207 Multi-Status and some items were not applied
+type PartialFailureError struct {
+	Succeeded []string
+	Failed    []ItemFailure
+}
+
+// FailedIDs returns the IDs of the items that were not applied
+func (e *PartialFailureError) FailedIDs() []string {
+	ids := make([]string, len(e.Failed))
+	for i, failure := range e.Failed {
+		ids[i] = failure.ID
+	}
+	return ids
+}
+
+func (e *PartialFailureError) Error() string {
+	total := len(e.Succeeded) + len(e.Failed)
+	if len(e.Failed) == 0 {
+		return fmt.Sprintf("partial success: 0 of %d items failed", total)
+	}
+	first := e.Failed[0]
+	return fmt.Sprintf("partial success: %d of %d items failed (first: %s: %s)",
+		len(e.Failed), total, first.ID, first.Message)
+}
+
+// multiStatusItem holds the fields used to tell a failed item from a
+// successful one in a multi-status response
+type multiStatusItem struct {
+	ID      string `json:"id"`
+	Status  int    `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// failureMessage returns why the item failed, or "" if it succeeded
+func (item multiStatusItem) failureMessage() string {
+	switch {
+	case item.Error != "":
+		return item.Error
+	case item.Status >= 400 && item.Message != "":
+		return item.Message
+	case item.Status >= 400:
+		return fmt.Sprintf("status %d", item.Status)
+	}
+	return ""
+}
+
+// splitItems separates the items of a multi-status response into successes
+// and failures. Requested IDs missing from the response are reported as
+// failed, since the API may only list the items it applied.
+func splitItems(requested []string, items json.RawMessage) ([]json.RawMessage, *PartialFailureError, error) {
+	var rawItems []json.RawMessage
+	if err := json.Unmarshal(items, &rawItems); err != nil {
+		return nil, nil, fmt.Errorf("unmarshaling items: %w", err)
+	}
+
+	result := &PartialFailureError{}
+	seen := make(map[string]bool, len(rawItems))
+	var succeeded []json.RawMessage
+	for _, raw := range rawItems {
+		var item multiStatusItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, nil, fmt.Errorf("unmarshaling item: %w", err)
+		}
+		seen[item.ID] = true
+
+		if msg := item.failureMessage(); msg != "" {
+			result.Failed = append(result.Failed, ItemFailure{ID: item.ID, Status: item.Status, Message: msg})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, item.ID)
+		succeeded = append(succeeded, raw)
+	}
+
+	for _, id := range requested {
+		if !seen[id] {
+			result.Failed = append(result.Failed, ItemFailure{ID: id, Message: "not applied"})
+		}
+	}
+
+	return succeeded, result, nil
+}
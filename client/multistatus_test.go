@@ -0,0 +1,111 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// multiStatusServer answers every request with a 207 and the given body
+func multiStatusServer(t *testing.T, body string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL)
+}
+
+func TestMultiStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		succeeded []string
+		failed    []ItemFailure
+	}{
+		{
+			name:      "all succeeded",
+			body:      `{"items":[{"id":"a"},{"id":"b","status":200},{"id":"c"}]}`,
+			succeeded: []string{"a", "b", "c"},
+		},
+		{
+			name:      "mixed",
+			body:      `{"items":[{"id":"a"},{"id":"b","status":404,"message":"block b not found"},{"id":"c","error":"locked"}]}`,
+			succeeded: []string{"a"},
+			failed: []ItemFailure{
+				{ID: "b", Status: 404, Message: "block b not found"},
+				{ID: "c", Message: "locked"},
+			},
+		},
+		{
+			name:      "missing items fail",
+			body:      `{"items":[{"id":"b"}]}`,
+			succeeded: []string{"b"},
+			failed: []ItemFailure{
+				{ID: "a", Message: "not applied"},
+				{ID: "c", Message: "not applied"},
+			},
+		},
+		{
+			name: "all failed",
+			body: `{"items":[{"id":"a","status":403},{"id":"b","status":409,"message":"conflict"},{"id":"c","status":500,"error":"internal"}]}`,
+			failed: []ItemFailure{
+				{ID: "a", Status: 403, Message: "status 403"},
+				{ID: "b", Status: 409, Message: "conflict"},
+				{ID: "c", Status: 500, Message: "internal"},
+			},
+		},
+	}
+
+	ids := []string{"a", "b", "c"}
+	for _, tt := range tests {
+		c := multiStatusServer(t, tt.body)
+		calls := map[string]func() ([]string, error){
+			"UpdateBlocks": func() ([]string, error) {
+				var changes []Block
+				for _, id := range ids {
+					changes = append(changes, Block{ID: id, Markdown: "new"})
+				}
+				updated, err := c.UpdateBlocks(UpdateRequest{Blocks: changes})
+				var updatedIDs []string
+				for _, block := range updated {
+					updatedIDs = append(updatedIDs, block.ID)
+				}
+				return updatedIDs, err
+			},
+			"DeleteBlocks": func() ([]string, error) { return c.DeleteBlocks(ids) },
+			"MoveBlocks": func() ([]string, error) {
+				return c.MoveBlocks(MoveRequest{BlockIDs: ids, Position: Position{Position: "end", PageID: "p"}})
+			},
+		}
+		for method, call := range calls {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				got, err := call()
+				if !slices.Equal(got, tt.succeeded) {
+					t.Errorf("succeeded = %v, want %v", got, tt.succeeded)
+				}
+
+				if len(tt.failed) == 0 {
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					return
+				}
+				var partial *PartialFailureError
+				if !errors.As(err, &partial) {
+					t.Fatalf("error = %v, want a *PartialFailureError", err)
+				}
+				if !slices.Equal(partial.Succeeded, tt.succeeded) {
+					t.Errorf("PartialFailureError.Succeeded = %v, want %v", partial.Succeeded, tt.succeeded)
+				}
+				if !slices.Equal(partial.Failed, tt.failed) {
+					t.Errorf("PartialFailureError.Failed = %+v, want %+v", partial.Failed, tt.failed)
+				}
+			})
+		}
+	}
+}
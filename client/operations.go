@@ -0,0 +1,765 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DuplicateBlock copies a block and its whole subtree to the given position
+// and returns the newly created blocks
+func (c *Client) DuplicateBlock(blockID string, position Position) ([]Block, error) {
+	block, err := c.FetchBlocks(blockID, -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching block %s: %w", blockID, err)
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Blocks:   []Block{copyWithoutIDs(*block)},
+		Position: position,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inserting copy of %s: %w", blockID, err)
+	}
+
+	return inserted, nil
+}
+
+// PageSummary identifies a page by ID and title
+type PageSummary struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ListPages returns the pages directly under the document root
+func (c *Client) ListPages() ([]PageSummary, error) {
+	root, err := c.FetchBlocks("", 1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching root: %w", err)
+	}
+
+	pages := []PageSummary{}
+	for i := range root.Content {
+		block := &root.Content[i]
+		if block.Type != "page" {
+			continue
+		}
+		pages = append(pages, PageSummary{ID: block.ID, Title: PageTitle(block)})
+	}
+
+	return pages, nil
+}
+
+// GetChildren returns the direct children of a block, without their own
+// children, or an empty slice for a leaf block
+func (c *Client) GetChildren(pageID string) ([]Block, error) {
+	page, err := c.FetchBlocks(pageID, 1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	if page.Content == nil {
+		return []Block{}, nil
+	}
+	return page.Content, nil
+}
+
+// ClearPage deletes every direct child of a page, leaving the page block
+// itself in place, and returns the deleted IDs
+func (c *Client) ClearPage(pageID string) ([]string, error) {
+	children, err := c.GetChildren(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) == 0 {
+		return []string{}, nil
+	}
+
+	ids := make([]string, len(children))
+	for i, child := range children {
+		ids[i] = child.ID
+	}
+
+	return c.DeleteBlocks(ids)
+}
+
+// FetchBlocksSince returns the blocks modified after the given time, without
+// their children. The API has no server-side modification filter, so this
+// fetches the whole document with metadata and filters locally; blocks
+// without a modification time are skipped.
+func (c *Client) FetchBlocksSince(since time.Time) ([]Block, error) {
+	root, err := c.FetchBlocks("", -1, true)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+
+	var modified []Block
+	collectModifiedSince(root, since, &modified)
+	return modified, nil
+}
+
+// collectModifiedSince appends the blocks in the tree modified after since
+func collectModifiedSince(block *Block, since time.Time, out *[]Block) {
+	if block.ModifiedAt != nil && block.ModifiedAt.After(since) {
+		b := *block
+		b.Content = nil
+		*out = append(*out, b)
+	}
+	for i := range block.Content {
+		collectModifiedSince(&block.Content[i], since, out)
+	}
+}
+
+// FetchBlocksFiltered fetches a block tree and prunes it to the blocks
+// matching pred plus their ancestors. The fetched block itself is always
+// returned, with no content if nothing below it matches.
+func (c *Client) FetchBlocksFiltered(id string, maxDepth int, pred func(Block) bool) (*Block, error) {
+	root, err := c.FetchBlocks(id, maxDepth, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pruneTree(root, pred)
+	return root, nil
+}
+
+// FetchBlocksByAuthor returns the blocks created by author, without their
+// children. Authors are only reported when fetchMetadata is true, so this
+// fetches the whole document with metadata and filters locally.
+func (c *Client) FetchBlocksByAuthor(author string) ([]Block, error) {
+	root, err := c.FetchBlocks("", -1, true)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+
+	authored, err := root.FlattenFilterLimit(c.maxTreeDepth, func(b *Block) bool { return b.Author == author })
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []Block
+	for _, block := range authored {
+		b := *block
+		b.Content = nil
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+// RemoveDuplicates deletes the direct children of a page whose markdown
+// repeats an earlier child's, keeping the first of each group, and returns
+// the deleted IDs. Comparison is as in FindDuplicates.
+func (c *Client) RemoveDuplicates(pageID string) ([]string, error) {
+	return c.removeDuplicates(pageID, false)
+}
+
+// RemoveDuplicatesFold is like RemoveDuplicates but compares markdown
+// case-insensitively, as in FindDuplicatesFold
+func (c *Client) RemoveDuplicatesFold(pageID string) ([]string, error) {
+	return c.removeDuplicates(pageID, true)
+}
+
+// removeDuplicates implements RemoveDuplicates and RemoveDuplicatesFold
+func (c *Client) removeDuplicates(pageID string, ignoreCase bool) ([]string, error) {
+	page, err := c.FetchBlocks(pageID, 1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	// Only direct children are compared, so deleting one never takes a
+	// kept block down with its subtree
+	for i := range page.Content {
+		page.Content[i].Content = nil
+	}
+
+	var ids []string
+	for _, group := range findDuplicates(page, ignoreCase) {
+		ids = append(ids, group[1:]...)
+	}
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	return c.DeleteBlocks(ids)
+}
+
+// DeleteBlocksBatched deletes blocks in chunks of at most batchSize IDs. It
+// keeps going when a chunk fails and returns every deleted ID together with
+// the combined errors of the failed chunks.
+func (c *Client) DeleteBlocksBatched(ids []string, batchSize int) ([]string, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+
+	deleted := []string{}
+	var errs []error
+	for start := 0; start < len(ids); start += batchSize {
+		end := min(start+batchSize, len(ids))
+		chunk, err := c.DeleteBlocks(ids[start:end])
+		deleted = append(deleted, chunk...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleting blocks %d-%d: %w", start, end-1, err))
+		}
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// InsertAfterMatch inserts markdown directly after the first block matching
+// pattern and returns the first inserted block. It returns ErrNoMatch if no
+// block matches.
+func (c *Client) InsertAfterMatch(pattern string, markdown string) (*Block, error) {
+	matches, err := c.Search(pattern, false, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("searching for %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Markdown: markdown,
+		Position: Position{Position: "after", SiblingID: matches[0].BlockID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inserting after %s: %w", matches[0].BlockID, err)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.New("insert returned no blocks")
+	}
+
+	return &inserted[0], nil
+}
+
+// AppendLogEntry adds text to the end of a page, prefixed with the current
+// UTC time in the client's log timestamp format
+func (c *Client) AppendLogEntry(pageID, text string) (*Block, error) {
+	timestamp := time.Now().UTC().Format(c.logTimestampFormat)
+
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Markdown: fmt.Sprintf("[%s] %s", timestamp, text),
+		Position: Position{Position: "end", PageID: pageID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("appending log entry to %s: %w", pageID, err)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.New("insert returned no blocks")
+	}
+
+	return &inserted[0], nil
+}
+
+// BlockExists reports whether a block exists, fetching only the block
+// itself. A not-found response yields false rather than an error.
+func (c *Client) BlockExists(id string) (bool, error) {
+	_, err := c.FetchBlocks(id, 0, false)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteSubtree deletes a block together with all of its descendants,
+// children before parents, and returns the deleted IDs
+func (c *Client) DeleteSubtree(id string) ([]string, error) {
+	block, err := c.FetchBlocks(id, -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching block %s: %w", id, err)
+	}
+
+	ids, err := subtreeIDs(block, c.maxTreeDepth)
+	if err != nil {
+		return nil, err
+	}
+	return c.DeleteBlocks(ids)
+}
+
+// Ping checks that the API is reachable by fetching the root block without
+// its children
+func (c *Client) Ping() error {
+	if _, err := c.FetchBlocks("", 0, false); err != nil {
+		return fmt.Errorf("pinging API: %w", err)
+	}
+	return nil
+}
+
+// SplitBlock splits a block's markdown on delimiter, keeps the first part in
+// the block and inserts the rest as text blocks right after it. Blank parts
+// are dropped. It returns the updated block followed by the new ones, or
+// just the block when there is nothing to split.
+func (c *Client) SplitBlock(blockID, delimiter string) ([]Block, error) {
+	if delimiter == "" {
+		return nil, errors.New("empty delimiter")
+	}
+
+	block, err := c.FetchBlocks(blockID, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching block %s: %w", blockID, err)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(block.Markdown, delimiter) {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) < 2 {
+		return []Block{*block}, nil
+	}
+
+	updated, err := c.UpdateBlocks(UpdateRequest{Blocks: []Block{{ID: blockID, Markdown: parts[0]}}})
+	if err != nil {
+		return nil, fmt.Errorf("updating block %s: %w", blockID, err)
+	}
+	if len(updated) > 0 {
+		block = &updated[0]
+	} else {
+		block.Markdown = parts[0]
+	}
+
+	rest := make([]Block, len(parts)-1)
+	for i, part := range parts[1:] {
+		rest[i] = Block{Type: "text", Markdown: part}
+	}
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Blocks:   rest,
+		Position: Position{Position: "after", SiblingID: blockID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inserting split parts of %s: %w", blockID, err)
+	}
+
+	return append([]Block{*block}, inserted...), nil
+}
+
+// MoveUp swaps a block with the sibling before it. Moving up the first
+// child is a no-op.
+func (c *Client) MoveUp(blockID string) error {
+	return c.moveAmongSiblings(blockID, -1)
+}
+
+// MoveDown swaps a block with the sibling after it. Moving down the last
+// child is a no-op.
+func (c *Client) MoveDown(blockID string) error {
+	return c.moveAmongSiblings(blockID, 1)
+}
+
+// moveAmongSiblings moves a block before the previous sibling or after the
+// next one. The API cannot look up a block's parent, so this fetches the
+// whole document to find its siblings.
+func (c *Client) moveAmongSiblings(blockID string, step int) error {
+	root, err := c.FetchBlocks("", -1, false)
+	if err != nil {
+		return fmt.Errorf("fetching document: %w", err)
+	}
+
+	parents, err := BuildParentIndexLimit(root, c.maxTreeDepth)
+	if err != nil {
+		return err
+	}
+	parent := parents[blockID]
+	if parent == nil {
+		return notFound(blockID)
+	}
+	index := slices.IndexFunc(parent.Content, func(b Block) bool { return b.ID == blockID })
+	target := index + step
+	if target < 0 || target >= len(parent.Content) {
+		return nil
+	}
+
+	position := Position{Position: "before", SiblingID: parent.Content[target].ID}
+	if step > 0 {
+		position.Position = "after"
+	}
+	if _, err := c.MoveBlocks(MoveRequest{BlockIDs: []string{blockID}, Position: position}); err != nil {
+		return fmt.Errorf("moving block %s: %w", blockID, err)
+	}
+	return nil
+}
+
+// InsertAtIndex inserts blocks into a page so the first of them ends up at
+// the given index among its children, or at the end when index is past the
+// last child
+func (c *Client) InsertAtIndex(pageID string, index int, blocks []Block) ([]Block, error) {
+	if index < 0 {
+		return nil, fmt.Errorf("negative index %d", index)
+	}
+
+	page, err := c.FetchBlocks(pageID, 1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	position := Position{Position: "end", PageID: page.ID}
+	if index < len(page.Content) {
+		position = Position{Position: "before", SiblingID: page.Content[index].ID}
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{Blocks: blocks, Position: position})
+	if err != nil {
+		return nil, fmt.Errorf("inserting into page %s: %w", pageID, err)
+	}
+	return inserted, nil
+}
+
+// ReplaceIfMatches sets a block's markdown to replacement only if it is
+// still expected, returning ErrPreconditionFailed otherwise. The API has no
+// conditional update, so a change landing between the check and the update
+// can still be overwritten; this only narrows that window to one request.
+func (c *Client) ReplaceIfMatches(blockID, expected, replacement string) (*Block, error) {
+	block, err := c.FetchBlocks(blockID, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching block %s: %w", blockID, err)
+	}
+	if block.Markdown != expected {
+		return nil, fmt.Errorf("block %s: %w", blockID, ErrPreconditionFailed)
+	}
+
+	updated, err := c.UpdateBlocks(UpdateRequest{Blocks: []Block{{ID: blockID, Markdown: replacement}}})
+	if err != nil {
+		return nil, fmt.Errorf("updating block %s: %w", blockID, err)
+	}
+	if len(updated) == 0 {
+		return nil, fmt.Errorf("updating block %s: no block returned", blockID)
+	}
+	return &updated[0], nil
+}
+
+// updateBatchSize is the number of blocks ReplaceAll and Transform update
+// per request
+const updateBatchSize = 50
+
+// ReplaceAll replaces every match of pattern in the document's markdown and
+// returns the number of blocks changed. Blocks are found with Search and
+// rewritten with Go's regexp, so replacement may refer to groups as $1.
+// Overlapping matches are all replaced: a run of matches that overlap is
+// replaced as a whole by their replacements in order, so replacing "aa"
+// with "b" in "aaa" gives "bb", while a match lying wholly inside an
+// earlier one, like the "oe" of a match on "Doe", is not replaced again. Each block is updated once however many
+// matches it holds, in batches of updateBatchSize; on error the count
+// covers the batches that succeeded.
+func (c *Client) ReplaceAll(pattern, replacement string, caseSensitive bool) (int, error) {
+	expr := pattern
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return 0, fmt.Errorf("compiling pattern: %w", err)
+	}
+	// later finds the next match after the first rune of its input, which
+	// stays in view so anchors and word boundaries see what precedes the
+	// match
+	later, err := regexp.Compile(`\A(?s:.)(?s:.*?)(` + expr + `)`)
+	if err != nil {
+		return 0, fmt.Errorf("compiling pattern: %w", err)
+	}
+
+	matches, err := c.Search(pattern, caseSensitive, 0, 0)
+	if err != nil {
+		return 0, fmt.Errorf("searching: %w", err)
+	}
+
+	var changes []Block
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if seen[match.BlockID] {
+			continue
+		}
+		seen[match.BlockID] = true
+
+		replaced := replaceOverlapping(re, later, match.Markdown, replacement)
+		if replaced != match.Markdown {
+			changes = append(changes, Block{ID: match.BlockID, Markdown: replaced})
+		}
+	}
+	return c.updateInBatches(changes)
+}
+
+// replaceOverlapping replaces every match of re in s, searching again with
+// later from the rune after each match's start so that matches beginning
+// inside an earlier one are found too. A run of overlapping matches is
+// replaced as a whole by the expansions of its matches in order; a match
+// that ends inside the run, such as a suffix of the match before, adds
+// nothing and is skipped.
+func replaceOverlapping(re, later *regexp.Regexp, s, replacement string) string {
+	var out []byte
+	copied, runEnd := 0, 0
+	m := re.FindStringSubmatchIndex(s)
+	for m != nil {
+		switch {
+		case m[0] >= runEnd:
+			out = append(out, s[copied:m[0]]...)
+			fallthrough
+		case m[1] > runEnd:
+			out = re.ExpandString(out, replacement, s, m)
+			runEnd = max(runEnd, m[1])
+			copied = runEnd
+		}
+		if m[0] == len(s) {
+			break
+		}
+
+		// later's first group is the whole of re, so its submatches
+		// after the full match line up with re's
+		start := m[0]
+		m = later.FindStringSubmatchIndex(s[start:])
+		if m == nil {
+			break
+		}
+		m = m[2:]
+		for i := range m {
+			if m[i] >= 0 {
+				m[i] += start
+			}
+		}
+	}
+	return string(append(out, s[copied:]...))
+}
+
+// updateInBatches updates blocks updateBatchSize at a time, stopping at the
+// first failed batch, and returns the number of blocks updated
+func (c *Client) updateInBatches(blocks []Block) (int, error) {
+	changed := 0
+	for start := 0; start < len(blocks); start += updateBatchSize {
+		end := min(start+updateBatchSize, len(blocks))
+		updated, err := c.UpdateBlocks(UpdateRequest{Blocks: blocks[start:end]})
+		changed += len(updated)
+		if err != nil {
+			return changed, fmt.Errorf("updating blocks %d-%d: %w", start, end-1, err)
+		}
+	}
+	return changed, nil
+}
+
+// GroupIntoPage creates a page with the given title in place of the first
+// of the blocks, in document order, and moves the blocks into it keeping
+// their relative order. Blocks nested inside other listed blocks move with
+// their ancestor. It returns the new page.
+func (c *Client) GroupIntoPage(blockIDs []string, title string) (*Block, error) {
+	if len(blockIDs) == 0 {
+		return nil, errors.New("no blocks to group")
+	}
+
+	root, err := c.FetchBlocks("", -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+
+	parents, err := BuildParentIndexLimit(root, c.maxTreeDepth)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(blockIDs))
+	for _, id := range blockIDs {
+		if parents[id] == nil {
+			return nil, notFound(id)
+		}
+		wanted[id] = true
+	}
+
+	blocks, err := root.FlattenLimit(c.maxTreeDepth)
+	if err != nil {
+		return nil, err
+	}
+	var ordered []string
+	for _, block := range blocks[1:] {
+		if !wanted[block.ID] {
+			continue
+		}
+		nested := false
+		for parent := parents[block.ID]; parent != nil && !nested; parent = parents[parent.ID] {
+			nested = wanted[parent.ID]
+		}
+		if !nested {
+			ordered = append(ordered, block.ID)
+		}
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Blocks:   []Block{NewPageBlock(title)},
+		Position: Position{Position: "before", SiblingID: ordered[0]},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating page: %w", err)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.New("insert returned no blocks")
+	}
+	page := &inserted[0]
+
+	if _, err := c.MoveBlocks(MoveRequest{
+		BlockIDs: ordered,
+		Position: Position{Position: "end", PageID: page.ID},
+	}); err != nil {
+		return page, fmt.Errorf("moving blocks into page %s: %w", page.ID, err)
+	}
+	return page, nil
+}
+
+// CreatePageWithContent adds a page with the given title at the end of the
+// parent page, inserts children into it and returns the page with the
+// inserted children as its content. If inserting the children fails, the
+// page has already been created; it is returned without content alongside
+// an error naming it.
+func (c *Client) CreatePageWithContent(parentPageID, title string, children []Block) (*Block, error) {
+	inserted, err := c.InsertBlocks(InsertRequest{
+		Blocks:   []Block{NewPageBlock(title)},
+		Position: Position{Position: "end", PageID: parentPageID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating page in %s: %w", parentPageID, err)
+	}
+	if len(inserted) == 0 {
+		return nil, errors.New("insert returned no blocks")
+	}
+	page := &inserted[0]
+	if len(children) == 0 {
+		return page, nil
+	}
+
+	content, err := c.InsertBlocks(InsertRequest{
+		Blocks:   children,
+		Position: Position{Position: "start", PageID: page.ID},
+	})
+	if err != nil {
+		return page, fmt.Errorf("page %s created, but inserting its content failed: %w", page.ID, err)
+	}
+	page.Content = content
+	return page, nil
+}
+
+// ExpandReferences fills in the content a shallow fetch left out of root.
+// The API marks neither truncated blocks nor lazy children, so a block
+// without content may be a leaf or cut off; rather than probing each one,
+// root's whole subtree is fetched once and copied into every block that has
+// no content, leaving blocks already loaded untouched.
+func (c *Client) ExpandReferences(root *Block) error {
+	if root == nil {
+		return errors.New("nil root block")
+	}
+
+	full, err := c.FetchBlocks(root.ID, -1, false)
+	if err != nil {
+		return fmt.Errorf("fetching block %s: %w", root.ID, err)
+	}
+	expandFrom(root, full)
+	return nil
+}
+
+// expandFrom copies content from the matching blocks of full into the
+// blocks of block's tree that have none
+func expandFrom(block, full *Block) {
+	if len(block.Content) == 0 {
+		if len(full.Content) > 0 {
+			block.Content = cloneBlock(*full).Content
+		}
+		return
+	}
+	for i := range block.Content {
+		id := block.Content[i].ID
+		if j := slices.IndexFunc(full.Content, func(b Block) bool { return b.ID == id }); j >= 0 {
+			expandFrom(&block.Content[i], &full.Content[j])
+		}
+	}
+}
+
+// markdownFetchConcurrency caps how many FetchMarkdownForBlocks requests
+// run at once
+const markdownFetchConcurrency = 8
+
+// FetchMarkdownForBlocks fetches each block, with its children, as markdown
+// and maps the IDs to the results. Fetches run concurrently, at most
+// markdownFetchConcurrency at a time. Blocks that fail are left out of the
+// map and their errors joined into the returned error.
+func (c *Client) FetchMarkdownForBlocks(ids []string) (map[string]string, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		errs     []error
+		markdown = make(map[string]string, len(ids))
+		slots    = make(chan struct{}, markdownFetchConcurrency)
+		seen     = make(map[string]bool, len(ids))
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			md, err := c.FetchBlocksMarkdown(id, -1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("fetching block %s: %w", id, err))
+				return
+			}
+			markdown[id] = md
+		}()
+	}
+	wg.Wait()
+
+	return markdown, errors.Join(errs...)
+}
+
+// RemoveEmptyBlocks deletes the blocks FindEmptyBlocks reports anywhere in
+// a page and returns their IDs
+func (c *Client) RemoveEmptyBlocks(pageID string) ([]string, error) {
+	page, err := c.FetchBlocks(pageID, -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	empty := FindEmptyBlocks(page)
+	if len(empty) == 0 {
+		return []string{}, nil
+	}
+
+	ids := make([]string, len(empty))
+	for i, block := range empty {
+		ids[i] = block.ID
+	}
+	return c.DeleteBlocks(ids)
+}
+
+// Transform calls fn on every block of the document, root included, and
+// pushes the blocks it reports as modified in batches of updateBatchSize,
+// returning the number updated. fn receives the block with its children
+// but only the block's own fields are sent; changes to its content are
+// ignored. On error the count covers the batches that succeeded.
+func (c *Client) Transform(fn func(b *Block) bool) (int, error) {
+	root, err := c.FetchBlocks("", -1, false)
+	if err != nil {
+		return 0, fmt.Errorf("fetching document: %w", err)
+	}
+
+	blocks, err := root.FlattenLimit(c.maxTreeDepth)
+	if err != nil {
+		return 0, err
+	}
+
+	var changes []Block
+	for _, block := range blocks {
+		if fn(block) {
+			change := *block
+			change.Content = nil
+			changes = append(changes, change)
+		}
+	}
+	return c.updateInBatches(changes)
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReplaceAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		markdown      []string
+		pattern       string
+		replacement   string
+		caseSensitive bool
+		want          []string
+		changed       int
+	}{
+		{
+			name:        "overlapping",
+			markdown:    []string{"aaa", "aaaa", "banana", "a"},
+			pattern:     "aa|ana",
+			replacement: "X",
+			want:        []string{"XX", "XXX", "bXX", "a"},
+			changed:     3,
+		},
+		{
+			name:        "overlapping groups",
+			markdown:    []string{"abcd"},
+			pattern:     `(\w)(\w)`,
+			replacement: "$2$1",
+			want:        []string{"bacbdc"},
+			changed:     1,
+		},
+		{
+			name:        "anchors keep their context",
+			markdown:    []string{"aaa", "abab ab"},
+			pattern:     `^a|\bab`,
+			replacement: "X",
+			want:        []string{"Xaa", "Xbab X"},
+			changed:     2,
+		},
+		{
+			name:        "groups",
+			markdown:    []string{"Doe, Jane", "no match"},
+			pattern:     `(\w+), (\w+)`,
+			replacement: "$2 $1",
+			want:        []string{"Jane Doe", "no match"},
+			changed:     1,
+		},
+		{
+			name:          "case sensitive",
+			markdown:      []string{"Todo todo TODO"},
+			pattern:       "todo",
+			replacement:   "done",
+			caseSensitive: true,
+			want:          []string{"Todo done TODO"},
+			changed:       1,
+		},
+		{
+			name:        "case insensitive",
+			markdown:    []string{"Todo todo TODO"},
+			pattern:     "todo",
+			replacement: "done",
+			want:        []string{"done done done"},
+			changed:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := Block{ID: "root"}
+			for _, md := range tt.markdown {
+				root.Content = append(root.Content, Block{Type: "text", Markdown: md})
+			}
+			f := NewFakeClient(root)
+			c, _ := newFakeServer(t, f)
+
+			changed, err := c.ReplaceAll(tt.pattern, tt.replacement, tt.caseSensitive)
+			if err != nil {
+				t.Fatalf("ReplaceAll: %v", err)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed %d blocks, want %d", changed, tt.changed)
+			}
+
+			doc, _ := f.FetchBlocks("root", -1, false)
+			for i, want := range tt.want {
+				if got := doc.Content[i].Markdown; got != want {
+					t.Errorf("block %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceAllBatches(t *testing.T) {
+	root := Block{ID: "root"}
+	blocks := 2*updateBatchSize + 7
+	for i := range blocks {
+		root.Content = append(root.Content, Block{Type: "text", Markdown: fmt.Sprintf("item %d", i)})
+	}
+	f := NewFakeClient(root)
+	c, _ := newFakeServer(t, f)
+
+	changed, err := c.ReplaceAll("item", "entry", true)
+	if err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	if changed != blocks {
+		t.Errorf("changed %d blocks, want %d", changed, blocks)
+	}
+	doc, _ := f.FetchBlocks("root", -1, false)
+	for i, block := range doc.Content {
+		if want := fmt.Sprintf("entry %d", i); block.Markdown != want {
+			t.Errorf("block %d = %q, want %q", i, block.Markdown, want)
+		}
+	}
+}
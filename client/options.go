@@ -0,0 +1,134 @@
+package client
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithMaxResponseBytes caps the size of response bodies. Zero or a negative
+// value disables the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		c.MaxResponseBytes = n
+	}
+}
+
+// WithTransport sets the RoundTripper used for every request, for example to
+// add mTLS, tracing, or an in-memory transport in tests
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.HTTPClient.Transport = rt
+	}
+}
+
+// WithClientTrace logs connection reuse, DNS, connect and TLS timings and
+// time to first byte for every request. A nil logger uses the standard
+// logger.
+func WithClientTrace(logger *log.Logger) Option {
+	return func(c *Client) {
+		if logger == nil {
+			logger = log.Default()
+		}
+		c.traceLogger = logger
+	}
+}
+
+// WithLogTimestampFormat sets the time layout AppendLogEntry uses for entry
+// timestamps. The default is RFC 3339.
+func WithLogTimestampFormat(layout string) Option {
+	return func(c *Client) {
+		c.logTimestampFormat = layout
+	}
+}
+
+// WithStrictTemplates makes InsertTemplate fail when a placeholder has no
+// value instead of inserting it unchanged
+func WithStrictTemplates() Option {
+	return func(c *Client) {
+		c.strictTemplates = true
+	}
+}
+
+// WithConnectionPool tunes the idle connection pool of the client's
+// transport. It has no effect on a custom transport set with WithTransport
+// unless that transport is an *http.Transport.
+func WithConnectionPool(maxIdleConns, maxIdleConnsPerHost int, idleConnTimeout time.Duration) Option {
+	return func(c *Client) {
+		transport, ok := c.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+		transport.MaxIdleConns = maxIdleConns
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+		transport.IdleConnTimeout = idleConnTimeout
+	}
+}
+
+// WithRetry sets how failed uploads are retried. A MaxAttempts of 1 or less
+// disables retries.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithProgress reports progress while uploading and downloading files and
+// while reading FetchBlocks responses
+func WithProgress(fn ProgressFunc) Option {
+	return func(c *Client) {
+		c.progress = fn
+	}
+}
+
+// WithStrictDecoding makes the client reject responses with fields it does
+// not model, to catch API changes during development. It is off by default,
+// keeping unknown block fields in Block.Extra.
+func WithStrictDecoding(strict bool) Option {
+	return func(c *Client) {
+		c.strictDecoding = strict
+	}
+}
+
+// WithMaxTreeDepth sets the deepest nesting FetchBlocks accepts before
+// failing with ErrTreeTooDeep. Zero or a negative value means
+// DefaultMaxTreeDepth.
+func WithMaxTreeDepth(n int) Option {
+	return func(c *Client) {
+		c.maxTreeDepth = n
+	}
+}
+
+// WithAuthToken sends token as a bearer token with every API request
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.authToken = token
+	}
+}
+
+// WithTimeout limits the time each request may take, including reading the
+// response body. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.HTTPClient.Timeout = d
+	}
+}
+
+// WithNormalizeHeadings makes InsertBlocks fix the heading levels of
+// inserted markdown, as NormalizeHeadings does for blocks, so they never
+// skip a level
+func WithNormalizeHeadings() Option {
+	return func(c *Client) {
+		c.normalizeHeadings = true
+	}
+}
@@ -0,0 +1,40 @@
+package client
+
+import "io"
+
+// ProgressFunc receives the number of bytes transferred so far and the
+// total, or -1 when the total is unknown
+type ProgressFunc func(done, total int64)
+
+// progressInterval is how many bytes pass between progress reports
+const progressInterval = 64 << 10
+
+// progressReader reports the bytes read through it every progressInterval
+// bytes and once more on reaching the total or EOF
+type progressReader struct {
+	io.Reader
+	fn       ProgressFunc
+	done     int64
+	total    int64
+	reported int64
+}
+
+// withProgress wraps r so reading it reports progress to fn, or returns r
+// unchanged when fn is nil
+func withProgress(r io.Reader, total int64, fn ProgressFunc) io.Reader {
+	if fn == nil {
+		return r
+	}
+	return &progressReader{Reader: r, fn: fn, total: total}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.done += int64(n)
+	finished := err == io.EOF || p.done == p.total
+	if p.done-p.reported >= progressInterval || (finished && p.done > p.reported) {
+		p.reported = p.done
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Jitter strategies spread out retries from clients that failed together
+const (
+	JitterNone  = "none"  // wait the full backoff
+	JitterFull  = "full"  // wait a random delay up to the backoff
+	JitterEqual = "equal" // wait half the backoff plus a random delay up to the other half
+)
+
+// RetryPolicy controls how failed uploads are retried. Attempt n backs off
+// BaseDelay * 2^(n-1), capped at MaxDelay, randomized by Jitter before
+// trying again.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      string // JitterNone, JitterFull or JitterEqual; empty means none
+}
+
+// DefaultRetryPolicy is used unless WithRetry sets another policy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	Jitter:      JitterFull,
+}
+
+// backoff returns the delay before retrying after the given failed attempt,
+// counting from 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	switch p.Jitter {
+	case JitterFull:
+		return randomDuration(delay)
+	case JitterEqual:
+		return delay/2 + randomDuration(delay-delay/2)
+	}
+	return delay
+}
+
+// randomDuration returns a random duration in [0, d]
+func randomDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+)
+
+// rootCache holds the document root's ID once RootPageID has fetched it. It
+// sits behind a pointer so copying a Client does not copy the lock.
+type rootCache struct {
+	mu sync.Mutex
+	id string
+}
+
+// RootPageID returns the ID of the document's root page, fetching the root
+// without children on first use and caching the ID afterwards. The root of
+// a document does not change, so the cache is only cleared by
+// ForgetRootPageID, for instance after pointing the client at another
+// document.
+func (c *Client) RootPageID() (string, error) {
+	if c.root == nil {
+		return c.fetchRootPageID() // a Client built without NewClient
+	}
+	c.root.mu.Lock()
+	defer c.root.mu.Unlock()
+
+	if c.root.id == "" {
+		id, err := c.fetchRootPageID()
+		if err != nil {
+			return "", err
+		}
+		c.root.id = id
+	}
+	return c.root.id, nil
+}
+
+// ForgetRootPageID clears the ID cached by RootPageID so the next call
+// fetches it again
+func (c *Client) ForgetRootPageID() {
+	if c.root == nil {
+		return
+	}
+	c.root.mu.Lock()
+	c.root.id = ""
+	c.root.mu.Unlock()
+}
+
+// fetchRootPageID fetches the root block alone and returns its ID
+func (c *Client) fetchRootPageID() (string, error) {
+	root, err := c.FetchBlocks("", 0, false)
+	if err != nil {
+		return "", fmt.Errorf("fetching root: %w", err)
+	}
+	return root.ID, nil
+}
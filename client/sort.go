@@ -0,0 +1,94 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// SortChildren reorders the direct children of a page by less, keeping the
+// order of equal blocks, and returns the IDs of the blocks that were moved.
+// Blocks on the longest run already in sorted order stay put; the rest are
+// moved in as few MoveBlocks calls as possible.
+func (c *Client) SortChildren(pageID string, less func(a, b Block) bool) ([]string, error) {
+	page, err := c.FetchBlocks(pageID, 1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	// order[i] is the current index of the block that sorts to position i
+	order := make([]int, len(page.Content))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(page.Content[order[i]], page.Content[order[j]])
+	})
+
+	// rank[i] is the sorted position of the block currently at index i
+	rank := make([]int, len(order))
+	for pos, i := range order {
+		rank[i] = pos
+	}
+	stay := make(map[int]bool)
+	for _, i := range longestIncreasingSubsequence(rank) {
+		stay[rank[i]] = true
+	}
+
+	// Walk the sorted order and move each run of misplaced blocks right
+	// after the block that precedes it
+	var moved []string
+	for pos := 0; pos < len(order); {
+		if stay[pos] {
+			pos++
+			continue
+		}
+
+		start := pos
+		var ids []string
+		for ; pos < len(order) && !stay[pos]; pos++ {
+			ids = append(ids, page.Content[order[pos]].ID)
+		}
+
+		position := Position{Position: "start", PageID: page.ID}
+		if start > 0 {
+			position = Position{Position: "after", SiblingID: page.Content[order[start-1]].ID}
+		}
+		if _, err := c.MoveBlocks(MoveRequest{BlockIDs: ids, Position: position}); err != nil {
+			return moved, fmt.Errorf("moving blocks in page %s: %w", pageID, err)
+		}
+		moved = append(moved, ids...)
+	}
+
+	return moved, nil
+}
+
+// longestIncreasingSubsequence returns the indices of a longest strictly
+// increasing subsequence of values, in order
+func longestIncreasingSubsequence(values []int) []int {
+	// tails[k] is the index of the smallest value ending an increasing
+	// subsequence of length k+1; prev links each index to its predecessor
+	var tails []int
+	prev := make([]int, len(values))
+	for i, v := range values {
+		k, _ := slices.BinarySearchFunc(tails, v, func(t, v int) int { return values[t] - v })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	if len(tails) == 0 {
+		return nil
+	}
+	lis := make([]int, len(tails))
+	for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = prev[i], k-1 {
+		lis[k] = i
+	}
+	return lis
+}
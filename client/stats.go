@@ -0,0 +1,62 @@
+package client
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// blockText returns the readable text of a single block: code verbatim,
+// table rows on separate lines with cells separated by spaces, and
+// everything else with markdown syntax stripped
+func blockText(block *Block) string {
+	switch block.Type {
+	case "code":
+		return block.Markdown
+	case "table":
+		rows := make([]string, len(block.Rows))
+		for i, row := range block.Rows {
+			rows[i] = strings.Join(row, " ")
+		}
+		return strings.Join(rows, "\n")
+	}
+	return StripMarkdown(block.Markdown)
+}
+
+// WordCount returns the number of words in the tree's text
+func (b *Block) WordCount() int {
+	count := 0
+	for _, block := range b.Flatten() {
+		count += len(strings.Fields(blockText(block)))
+	}
+	return count
+}
+
+// CharCount returns the number of characters in the tree's text, including
+// spaces
+func (b *Block) CharCount() int {
+	count := 0
+	for _, block := range b.Flatten() {
+		count += utf8.RuneCountInString(blockText(block))
+	}
+	return count
+}
+
+// WordCountByPage returns the number of words on each page in the tree,
+// keyed by page ID. A page's count covers its title and the blocks directly
+// on it, not those of nested pages.
+func (b *Block) WordCountByPage() map[string]int {
+	counts := make(map[string]int)
+	countPageWords(b, b.ID, counts)
+	return counts
+}
+
+// countPageWords adds the words of the tree to the page enclosing each block
+func countPageWords(block *Block, pageID string, counts map[string]int) {
+	if block.Type == "page" {
+		pageID = block.ID
+	}
+	counts[pageID] += len(strings.Fields(blockText(block)))
+	for i := range block.Content {
+		countPageWords(&block.Content[i], pageID, counts)
+	}
+}
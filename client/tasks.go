@@ -0,0 +1,56 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// taskMarkerPattern matches the list marker and checkbox leading a task,
+// capturing the marker and the box's state
+var taskMarkerPattern = regexp.MustCompile(`^(\s*(?:[-*+]|\d+\.)\s+)\[([ xX])\]`)
+
+// SetTasksChecked checks or unchecks the tasks with the given IDs in one
+// update request and returns the updated blocks. The API has no task state
+// field, so the checkbox in each task's markdown is rewritten; the document
+// is fetched once to read it. Tasks already in the requested state are not
+// updated, and nothing is updated if any block is missing or not a task.
+func (c *Client) SetTasksChecked(blockIDs []string, checked bool) ([]Block, error) {
+	root, err := c.FetchBlocks("", -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document: %w", err)
+	}
+
+	state := " "
+	if checked {
+		state = "x"
+	}
+
+	var changes []Block
+	for _, id := range blockIDs {
+		block, err := root.FindByIDLimit(id, c.maxTreeDepth)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, notFound(id)
+		}
+		m := taskMarkerPattern.FindStringSubmatchIndex(block.Markdown)
+		if m == nil {
+			return nil, fmt.Errorf("block %s is not a task", id)
+		}
+		if (block.Markdown[m[4]:m[5]] != " ") == checked {
+			continue
+		}
+		md := block.Markdown[:m[4]] + state + block.Markdown[m[5]:]
+		changes = append(changes, Block{ID: id, Markdown: md})
+	}
+
+	if len(changes) == 0 {
+		return []Block{}, nil
+	}
+	updated, err := c.UpdateBlocks(UpdateRequest{Blocks: changes})
+	if err != nil {
+		return updated, fmt.Errorf("updating tasks: %w", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderPattern matches a {{name}} template placeholder
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// ExpandTemplate returns a deep copy of the template blocks, without IDs,
+// with {{name}} placeholders in their markdown replaced from vars. In strict
+// mode a placeholder with no value is an error; otherwise it is left as is.
+func ExpandTemplate(template []Block, vars map[string]string, strict bool) ([]Block, error) {
+	missing := make(map[string]bool)
+	blocks := make([]Block, len(template))
+	for i, block := range template {
+		blocks[i] = copyWithoutIDs(block)
+		expandPlaceholders(&blocks[i], vars, missing)
+	}
+
+	if strict && len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unreplaced placeholders: %s", strings.Join(names, ", "))
+	}
+
+	return blocks, nil
+}
+
+// expandPlaceholders substitutes vars throughout the tree, recording the
+// names of placeholders that have no value
+func expandPlaceholders(block *Block, vars map[string]string, missing map[string]bool) {
+	block.Markdown = placeholderPattern.ReplaceAllStringFunc(block.Markdown, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		missing[name] = true
+		return placeholder
+	})
+	for i := range block.Content {
+		expandPlaceholders(&block.Content[i], vars, missing)
+	}
+}
+
+// InsertTemplate expands the template with vars and inserts the result. An
+// empty position means the end of pageID, and a start or end position
+// without a page ID targets pageID. Unreplaced placeholders are an error
+// when the client was created with WithStrictTemplates.
+func (c *Client) InsertTemplate(pageID string, template []Block, vars map[string]string, position Position) ([]Block, error) {
+	blocks, err := ExpandTemplate(template, vars, c.strictTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err = defaultPosition(pageID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.InsertBlocks(InsertRequest{Blocks: blocks, Position: position})
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// timestampLayouts are the string formats accepted for metadata timestamps
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// Timestamp is a metadata time that decodes from RFC 3339 and similar
+// strings as well as from Unix epoch milliseconds. It marshals as RFC 3339.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON accepts a timestamp string or a number of milliseconds
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		for _, layout := range timestampLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				t.Time = parsed
+				return nil
+			}
+		}
+		return fmt.Errorf("unrecognized timestamp format %q", s)
+	}
+
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return fmt.Errorf("unrecognized timestamp %s", data)
+	}
+	t.Time = time.UnixMilli(int64(ms)).UTC()
+	return nil
+}
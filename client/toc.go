@@ -0,0 +1,96 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TOCEntry is a heading in a table of contents, with the headings nested
+// under it
+type TOCEntry struct {
+	BlockID  string
+	Title    string
+	Level    int // 1 for h1 through 6 for h6
+	Children []TOCEntry
+}
+
+// headingLevel returns the level of a heading block, or 0 for any other
+func headingLevel(block *Block) int {
+	style := block.TextStyle
+	if len(style) == 2 && style[0] == 'h' && style[1] >= '1' && style[1] <= '6' {
+		return int(style[1] - '0')
+	}
+	return 0
+}
+
+// GenerateTOC returns the headings in the tree in document order, each
+// nested under the closest preceding heading of a lower level
+func GenerateTOC(root *Block) []TOCEntry {
+	var top []TOCEntry
+	// stack holds the path of open entries; pointers stay valid because an
+	// entry's children are only appended to while it is the deepest open one
+	var stack []*TOCEntry
+	for _, block := range root.Flatten() {
+		level := headingLevel(block)
+		if level == 0 {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		entry := TOCEntry{
+			BlockID: block.ID,
+			Title:   strings.TrimSpace(StripMarkdown(block.Markdown)),
+			Level:   level,
+		}
+
+		siblings := &top
+		if len(stack) > 0 {
+			siblings = &stack[len(stack)-1].Children
+		}
+		*siblings = append(*siblings, entry)
+		stack = append(stack, &(*siblings)[len(*siblings)-1])
+	}
+	return top
+}
+
+// RenderTOC renders entries as a nested markdown list of links to the
+// heading blocks
+func RenderTOC(entries []TOCEntry) string {
+	var lines []string
+	var render func(entries []TOCEntry, depth int)
+	render = func(entries []TOCEntry, depth int) {
+		for _, entry := range entries {
+			lines = append(lines, strings.Repeat("  ", depth)+"- "+BlockMention(entry.Title, entry.BlockID, ""))
+			render(entry.Children, depth+1)
+		}
+	}
+	render(entries, 0)
+	return strings.Join(lines, "\n")
+}
+
+// InsertTOC inserts a table of contents of the page's headings at the top
+// of the page and returns the inserted blocks, or nil if the page has no
+// headings
+func (c *Client) InsertTOC(pageID string) ([]Block, error) {
+	page, err := c.FetchBlocks(pageID, -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("fetching page %s: %w", pageID, err)
+	}
+
+	toc := GenerateTOC(page)
+	if len(toc) == 0 {
+		return nil, nil
+	}
+
+	req, err := InsertRequestFromMarkdown(page.ID, RenderTOC(toc), "start")
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := c.InsertBlocks(req)
+	if err != nil {
+		return nil, fmt.Errorf("inserting table of contents: %w", err)
+	}
+	return inserted, nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestTrace collects connection timings for a single request
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tls          time.Duration
+	ttfb         time.Duration
+	reused       bool
+}
+
+// newRequestTrace starts timing a request
+func newRequestTrace() *requestTrace {
+	return &requestTrace{start: time.Now()}
+}
+
+// clientTrace returns hooks recording into the trace. The hooks may run on
+// different goroutines, so each takes the lock.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(fn func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		fn()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { t.dns = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			record(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			record(func() { t.connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			record(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { t.tls = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func() { t.reused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			record(func() { t.ttfb = time.Since(t.start) })
+		},
+	}
+}
+
+func (t *requestTrace) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return fmt.Sprintf("reused=%t dns=%s connect=%s tls=%s ttfb=%s total=%s",
+		t.reused, t.dns, t.connect, t.tls, t.ttfb, time.Since(t.start))
+}
@@ -0,0 +1,243 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxTreeDepth is the deepest nesting Walk and fetched trees accept
+// unless another limit is given
+const DefaultMaxTreeDepth = 1000
+
+// Walk calls fn for every block in the tree in depth-first order, with the
+// block's depth below b, stopping at the first error fn returns. It fails
+// with ErrTreeTooDeep below maxDepth levels, where zero or a negative value
+// means DefaultMaxTreeDepth, and with ErrTreeCycle when a block ID repeats.
+func (b *Block) Walk(maxDepth int, fn func(block *Block, depth int) error) error {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxTreeDepth
+	}
+	return walk(b, 0, maxDepth, make(map[string]bool), fn)
+}
+
+// walk visits block and its descendants for Walk, recording the IDs seen
+func walk(block *Block, depth, maxDepth int, seen map[string]bool, fn func(*Block, int) error) error {
+	if depth > maxDepth {
+		return fmt.Errorf("depth %d: %w", depth, ErrTreeTooDeep)
+	}
+	if block.ID != "" {
+		if seen[block.ID] {
+			return fmt.Errorf("block %s: %w", block.ID, ErrTreeCycle)
+		}
+		seen[block.ID] = true
+	}
+
+	if err := fn(block, depth); err != nil {
+		return err
+	}
+	for i := range block.Content {
+		if err := walk(&block.Content[i], depth+1, maxDepth, seen, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckTree reports whether the tree is safe to walk: no deeper than
+// maxDepth, as for Walk, and without repeated block IDs
+func (b *Block) CheckTree(maxDepth int) error {
+	return b.Walk(maxDepth, func(*Block, int) error { return nil })
+}
+
+// Flatten returns every block in the tree in depth-first order, starting
+// with the block itself. The returned pointers refer into the tree. A tree
+// that fails CheckTree with DefaultMaxTreeDepth gives nil; FlattenLimit
+// reports why.
+func (b *Block) Flatten() []*Block {
+	blocks, _ := b.FlattenLimit(0)
+	return blocks
+}
+
+// FlattenLimit is Flatten for a tree checked against maxDepth as for Walk
+func (b *Block) FlattenLimit(maxDepth int) ([]*Block, error) {
+	return b.FlattenFilterLimit(maxDepth, func(*Block) bool { return true })
+}
+
+// FlattenFilter returns the blocks in the tree, in depth-first order, for
+// which pred returns true. Like Flatten it gives nil for a tree that fails
+// CheckTree with DefaultMaxTreeDepth.
+func (b *Block) FlattenFilter(pred func(*Block) bool) []*Block {
+	blocks, _ := b.FlattenFilterLimit(0, pred)
+	return blocks
+}
+
+// FlattenFilterLimit is FlattenFilter for a tree checked against maxDepth
+// as for Walk
+func (b *Block) FlattenFilterLimit(maxDepth int, pred func(*Block) bool) ([]*Block, error) {
+	var blocks []*Block
+	err := b.Walk(maxDepth, func(block *Block, _ int) error {
+		if pred(block) {
+			blocks = append(blocks, block)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// errFound stops the walk of FindByIDLimit at the block it looks for
+var errFound = errors.New("found")
+
+// FindByID returns the block with the given ID in the tree, or nil. It also
+// gives nil when the search reaches a part of the tree that fails CheckTree
+// with DefaultMaxTreeDepth.
+func (b *Block) FindByID(id string) *Block {
+	found, _ := b.FindByIDLimit(id, 0)
+	return found
+}
+
+// FindByIDLimit is FindByID for a tree checked against maxDepth as for
+// Walk, up to the block found
+func (b *Block) FindByIDLimit(id string, maxDepth int) (*Block, error) {
+	var found *Block
+	err := b.Walk(maxDepth, func(block *Block, _ int) error {
+		if block.ID == id {
+			found = block
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && err != errFound {
+		return nil, err
+	}
+	return found, nil
+}
+
+// BuildParentIndex maps the ID of every block below root to its parent, so
+// callers can walk up the tree after a fetch. The returned pointers refer
+// into the tree. A tree that fails CheckTree with DefaultMaxTreeDepth gives
+// nil.
+func BuildParentIndex(root *Block) map[string]*Block {
+	parents, _ := BuildParentIndexLimit(root, 0)
+	return parents
+}
+
+// BuildParentIndexLimit is BuildParentIndex for a tree checked against
+// maxDepth as for Walk
+func BuildParentIndexLimit(root *Block, maxDepth int) (map[string]*Block, error) {
+	parents := make(map[string]*Block)
+	err := root.Walk(maxDepth, func(block *Block, _ int) error {
+		for i := range block.Content {
+			parents[block.Content[i].ID] = block
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parents, nil
+}
+
+// subtreeIDs returns the IDs in the tree with every block listed after its
+// descendants, checking the tree against maxDepth as for Walk
+func subtreeIDs(root *Block, maxDepth int) ([]string, error) {
+	blocks, err := root.FlattenLimit(maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(blocks))
+	for i, block := range blocks {
+		ids[len(blocks)-1-i] = block.ID
+	}
+	return ids, nil
+}
+
+// ImageRef describes an image block found in a tree
+type ImageRef struct {
+	BlockID  string
+	URL      string
+	AltText  string
+	FileName string
+	MimeType string
+	FileSize int64
+}
+
+// ListImages returns the image blocks in the tree in depth-first order
+func ListImages(root *Block) []ImageRef {
+	var images []ImageRef
+	for _, block := range root.FlattenFilter(func(b *Block) bool { return b.Type == "image" }) {
+		images = append(images, ImageRef{
+			BlockID:  block.ID,
+			URL:      block.URL,
+			AltText:  block.AltText,
+			FileName: block.FileName,
+			MimeType: block.MimeType,
+			FileSize: block.FileSize,
+		})
+	}
+	return images
+}
+
+// FindDuplicates maps normalized markdown to the IDs of the blocks below
+// root that share it, in depth-first order, for every markdown held by more
+// than one block. Markdown is trimmed before comparing; blocks without
+// markdown are skipped.
+func FindDuplicates(root *Block) map[string][]string {
+	return findDuplicates(root, false)
+}
+
+// FindDuplicatesFold is like FindDuplicates but compares markdown
+// case-insensitively, keying the groups by lower-cased markdown
+func FindDuplicatesFold(root *Block) map[string][]string {
+	return findDuplicates(root, true)
+}
+
+// findDuplicates implements FindDuplicates and FindDuplicatesFold
+func findDuplicates(root *Block, ignoreCase bool) map[string][]string {
+	groups := make(map[string][]string)
+	for _, block := range root.Flatten()[1:] {
+		key := strings.TrimSpace(block.Markdown)
+		if key == "" {
+			continue
+		}
+		if ignoreCase {
+			key = strings.ToLower(key)
+		}
+		groups[key] = append(groups[key], block.ID)
+	}
+
+	for key, ids := range groups {
+		if len(ids) < 2 {
+			delete(groups, key)
+		}
+	}
+	return groups
+}
+
+// FindEmptyBlocks returns the text blocks below root, in depth-first order,
+// that have neither markdown beyond whitespace nor children. Blocks of any
+// other type, such as pages, dividers, media or spacers, are never
+// considered empty since their type is their content.
+func FindEmptyBlocks(root *Block) []*Block {
+	return root.FlattenFilter(func(b *Block) bool {
+		return b != root && (b.Type == "" || b.Type == "text") &&
+			strings.TrimSpace(b.Markdown) == "" && len(b.Content) == 0
+	})
+}
+
+// pruneTree removes the descendants of block that neither match pred nor
+// have a matching descendant, and reports whether anything in the tree
+// matched
+func pruneTree(block *Block, pred func(Block) bool) bool {
+	kept := block.Content[:0]
+	for i := range block.Content {
+		if pruneTree(&block.Content[i], pred) {
+			kept = append(kept, block.Content[i])
+		}
+	}
+	block.Content = kept
+	return len(kept) > 0 || pred(*block)
+}
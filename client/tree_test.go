@@ -0,0 +1,150 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// duplicatesDoc has repeated lines differing in case and whitespace, one
+// of them nested
+var duplicatesDoc = Block{ID: "root", Content: []Block{
+	{ID: "a", Markdown: "Log line"},
+	{ID: "b", Markdown: "other"},
+	{ID: "c", Markdown: "  Log line "},
+	{ID: "d", Markdown: "log LINE"},
+	{ID: "e", Type: "page", Markdown: "Page", Content: []Block{{ID: "f", Markdown: "other"}}},
+	{ID: "g", Markdown: ""},
+	{ID: "h", Markdown: " "},
+}}
+
+func TestFindDuplicates(t *testing.T) {
+	got := FindDuplicates(&duplicatesDoc)
+	want := map[string][]string{
+		"Log line": {"a", "c"},
+		"other":    {"b", "f"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDuplicates = %v, want %v", got, want)
+	}
+
+	got = FindDuplicatesFold(&duplicatesDoc)
+	want = map[string][]string{
+		"log line": {"a", "c", "d"},
+		"other":    {"b", "f"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDuplicatesFold = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		remove  func(*Client, string) ([]string, error)
+		deleted []string
+	}{
+		{"exact", (*Client).RemoveDuplicates, []string{"c"}},
+		{"fold", (*Client).RemoveDuplicatesFold, []string{"c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFakeClient(duplicatesDoc)
+			c, _ := newFakeServer(t, f)
+
+			// Only direct children are compared, so the nested "other" stays
+			deleted, err := tt.remove(c, "root")
+			if err != nil {
+				t.Fatalf("removing duplicates: %v", err)
+			}
+			slices.Sort(deleted)
+			if !slices.Equal(deleted, tt.deleted) {
+				t.Errorf("deleted %v, want %v", deleted, tt.deleted)
+			}
+			for _, id := range tt.deleted {
+				if _, err := f.FetchBlocks(id, 0, false); !IsNotFound(err) {
+					t.Errorf("block %s still exists", id)
+				}
+			}
+
+			// A second pass finds nothing left to delete
+			deleted, err = tt.remove(c, "root")
+			if err != nil || deleted == nil || len(deleted) != 0 {
+				t.Errorf("second pass deleted %#v, %v, want an empty slice", deleted, err)
+			}
+		})
+	}
+}
+
+// chain returns a tree of n levels below a root, one block per level, with
+// the deepest block's ID "leaf"
+func chain(n int) *Block {
+	root := &Block{ID: "root"}
+	block := root
+	for i := range n {
+		block.Content = []Block{{ID: fmt.Sprintf("b%d", i)}}
+		block = &block.Content[0]
+	}
+	block.ID = "leaf"
+	return root
+}
+
+func TestTreeHelpersRejectDeepTrees(t *testing.T) {
+	root := chain(5)
+	const limit = 3
+
+	checks := map[string]func() error{
+		"FlattenLimit": func() error {
+			_, err := root.FlattenLimit(limit)
+			return err
+		},
+		"FlattenFilterLimit": func() error {
+			_, err := root.FlattenFilterLimit(limit, func(*Block) bool { return false })
+			return err
+		},
+		"FindByIDLimit": func() error {
+			_, err := root.FindByIDLimit("leaf", limit)
+			return err
+		},
+		"BuildParentIndexLimit": func() error {
+			_, err := BuildParentIndexLimit(root, limit)
+			return err
+		},
+		"treeDepth": func() error {
+			_, err := treeDepth(root, limit)
+			return err
+		},
+		"subtreeIDs": func() error {
+			_, err := subtreeIDs(root, limit)
+			return err
+		},
+	}
+	for name, check := range checks {
+		if err := check(); !errors.Is(err, ErrTreeTooDeep) {
+			t.Errorf("%s with limit %d = %v, want ErrTreeTooDeep", name, limit, err)
+		}
+	}
+
+	if got, err := treeDepth(root, 5); got != 5 || err != nil {
+		t.Errorf("treeDepth with limit 5 = %d, %v, want 5", got, err)
+	}
+	if found, err := root.FindByIDLimit("leaf", 5); err != nil || found == nil {
+		t.Errorf("FindByIDLimit with limit 5 = %v, %v, want the leaf", found, err)
+	}
+
+	deep := chain(DefaultMaxTreeDepth + 1)
+	if got := deep.Flatten(); got != nil {
+		t.Errorf("Flatten of a tree deeper than DefaultMaxTreeDepth returned %d blocks, want nil", len(got))
+	}
+	if got := deep.FlattenFilter(func(*Block) bool { return true }); got != nil {
+		t.Errorf("FlattenFilter of a tree deeper than DefaultMaxTreeDepth returned %d blocks, want nil", len(got))
+	}
+	if got := deep.FindByID("leaf"); got != nil {
+		t.Errorf("FindByID in a tree deeper than DefaultMaxTreeDepth = %+v, want nil", got)
+	}
+	if got := BuildParentIndex(deep); got != nil {
+		t.Errorf("BuildParentIndex of a tree deeper than DefaultMaxTreeDepth has %d entries, want nil", len(got))
+	}
+}
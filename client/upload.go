@@ -0,0 +1,258 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMimeType is used for files whose extension has no known MIME type
+const DefaultMimeType = "application/octet-stream"
+
+// ErrUploadSizeMismatch is returned when the size of an uploaded file does
+// not match the size of its input
+var ErrUploadSizeMismatch = errors.New("uploaded size mismatch")
+
+// DetectMimeType returns the MIME type for a file name's extension, without
+// parameters such as charset, or DefaultMimeType when it is unknown
+func DetectMimeType(fileName string) string {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName)))
+	if mimeType == "" {
+		return DefaultMimeType
+	}
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return mimeType
+}
+
+// uploadExpiryMargin is how long before its expiry UploadFile stops using a
+// pre-signed URL, leaving time for the upload itself
+const uploadExpiryMargin = 30 * time.Second
+
+// amzDateLayout is the timestamp format of the X-Amz-Date parameter
+const amzDateLayout = "20060102T150405Z"
+
+// ExpiresAt returns when the pre-signed UploadURL stops working, read from
+// its S3 X-Amz-Date and X-Amz-Expires parameters, or the zero time if the
+// URL does not carry them
+func (l *UploadLinkResponse) ExpiresAt() time.Time {
+	u, err := url.Parse(l.UploadURL)
+	if err != nil {
+		return time.Time{}
+	}
+	query := u.Query()
+	signed, err := time.Parse(amzDateLayout, query.Get("X-Amz-Date"))
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return time.Time{}
+	}
+	return signed.Add(time.Duration(seconds) * time.Second)
+}
+
+// IsExpired reports whether the UploadURL has expired. A URL without a known
+// expiry is never reported as expired.
+func (l *UploadLinkResponse) IsExpired() bool {
+	return l.expiresWithin(0)
+}
+
+// expiresWithin reports whether the UploadURL expires within d from now
+func (l *UploadLinkResponse) expiresWithin(d time.Duration) bool {
+	expires := l.ExpiresAt()
+	return !expires.IsZero() && !time.Now().Add(d).Before(expires)
+}
+
+// UploadFile uploads a file through a pre-signed URL and returns the URLs
+// for it; use RawURL as the url of an image, video or file block. The PUT is
+// retried with backoff according to the client's retry policy, which is why
+// the input must be seekable. Afterwards the upload is verified with a HEAD
+// request to RawURL comparing its size to the input. A fresh URL is
+// requested whenever the current one is about to expire.
+func (c *Client) UploadFile(fileName, mimeType string, r io.ReadSeeker) (*UploadLinkResponse, error) {
+	// The PUT must carry the same content type the URL was signed for
+	if mimeType == "" {
+		mimeType = DetectMimeType(fileName)
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("sizing %s: %w", fileName, err)
+	}
+
+	link, err := c.GenerateUploadURL(fileName, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("generating upload URL: %w", err)
+	}
+
+	policy := c.retry
+	for attempt := 1; ; attempt++ {
+		if link.expiresWithin(uploadExpiryMargin) {
+			if link, err = c.GenerateUploadURL(fileName, mimeType); err != nil {
+				return nil, fmt.Errorf("renewing upload URL: %w", err)
+			}
+		}
+		err = c.putFile(link.UploadURL, mimeType, r, size)
+		var apiErr *APIError
+		if err == nil || attempt >= policy.MaxAttempts || (errors.As(err, &apiErr) && !retryableStatus(apiErr.StatusCode)) {
+			break
+		}
+		time.Sleep(policy.backoff(attempt))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s: %w", fileName, err)
+	}
+
+	if err := c.verifyUpload(link.RawURL, size); err != nil {
+		return nil, fmt.Errorf("verifying %s: %w", fileName, err)
+	}
+	return link, nil
+}
+
+// putFile sends the whole file to a pre-signed upload URL
+func (c *Client) putFile(uploadURL, mimeType string, r io.ReadSeeker, size int64) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding file: %w", err)
+	}
+
+	// The body is wrapped so the transport cannot close the caller's file
+	req, err := c.newRequest("PUT", uploadURL, io.NopCloser(withProgress(r, size, c.progress)))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", mimeType)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp)
+	}
+	return nil
+}
+
+// verifyUpload checks that the file at rawURL has the expected size
+func (c *Client) verifyUpload(rawURL string, size int64) error {
+	req, err := c.newRequest("HEAD", rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	if resp.ContentLength != size {
+		return fmt.Errorf("%w: sent %d bytes, stored %d", ErrUploadSizeMismatch, size, resp.ContentLength)
+	}
+	return nil
+}
+
+// imageRefPattern matches a markdown image, capturing the alt text, the
+// destination and an optional title
+var imageRefPattern = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(\s+"[^"]*")?\)`)
+
+// InsertMarkdownWithLocalImages uploads the local files referenced by images
+// in markdown, rewrites the images to point at the uploads and inserts the
+// result. Paths are resolved against baseDir and must stay inside it:
+// absolute paths and paths climbing out with ".." are an error, so
+// untrusted markdown cannot upload arbitrary files, though symbolic links
+// inside baseDir are followed. Images with a URL scheme or a
+// protocol-relative URL are left alone. Each file is uploaded once however
+// often it is referenced. An empty position means the end of pageID, and a
+// start or end position without a page ID targets pageID.
+func (c *Client) InsertMarkdownWithLocalImages(pageID, markdown, baseDir string, position Position) ([]Block, error) {
+	position, err := defaultPosition(pageID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded := make(map[string]string)
+	var uploadErr error
+	markdown = imageRefPattern.ReplaceAllStringFunc(markdown, func(ref string) string {
+		m := imageRefPattern.FindStringSubmatch(ref)
+		if uploadErr != nil {
+			return ref
+		}
+		path, ok, err := localImagePath(m[2], baseDir)
+		if err != nil {
+			uploadErr = err
+			return ref
+		}
+		if !ok {
+			return ref
+		}
+
+		rawURL, ok := uploaded[path]
+		if !ok {
+			link, err := c.uploadLocalFile(path)
+			if err != nil {
+				uploadErr = err
+				return ref
+			}
+			rawURL = link.RawURL
+			uploaded[path] = rawURL
+		}
+		return fmt.Sprintf("![%s](%s%s)", m[1], rawURL, m[3])
+	})
+	if uploadErr != nil {
+		return nil, uploadErr
+	}
+
+	inserted, err := c.InsertBlocks(InsertRequest{Markdown: markdown, Position: position})
+	if err != nil {
+		return nil, fmt.Errorf("inserting markdown: %w", err)
+	}
+	return inserted, nil
+}
+
+// localImagePath resolves an image destination to a file path inside
+// baseDir, reporting false for remote URLs and an error for paths that are
+// absolute or lead out of baseDir
+func localImagePath(dest, baseDir string) (string, bool, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Host != "" || (u.Scheme != "" && u.Scheme != "file") {
+		return "", false, nil
+	}
+
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque // A relative file URL such as file:img.png
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsLocal(path) {
+		return "", false, fmt.Errorf("image path %q is outside %s", dest, baseDir)
+	}
+	return filepath.Join(baseDir, path), true, nil
+}
+
+// uploadLocalFile uploads the file at path under its base name
+func (c *Client) uploadLocalFile(path string) (*UploadLinkResponse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening image: %w", err)
+	}
+	defer f.Close()
+
+	return c.UploadFile(filepath.Base(path), "", f)
+}
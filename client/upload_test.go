@@ -0,0 +1,39 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalImagePath(t *testing.T) {
+	base := filepath.FromSlash("/docs/import")
+	tests := []struct {
+		dest    string
+		want    string
+		local   bool
+		wantErr bool
+	}{
+		{dest: "img.png", want: "/docs/import/img.png", local: true},
+		{dest: "assets/img%20one.png", want: "/docs/import/assets/img one.png", local: true},
+		{dest: "./assets/../img.png", want: "/docs/import/img.png", local: true},
+		{dest: "file:img.png", want: "/docs/import/img.png", local: true},
+		{dest: "https://example.com/img.png"},
+		{dest: "//cdn.example.com/img.png"},
+		{dest: "data:image/png;base64,AAAA"},
+		{dest: "../secret.png", wantErr: true},
+		{dest: "assets/../../secret.png", wantErr: true},
+		{dest: "%2e%2e/secret.png", wantErr: true},
+		{dest: "/etc/passwd", wantErr: true},
+		{dest: "file:///etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, local, err := localImagePath(tt.dest, base)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("localImagePath(%q) error = %v, want error %v", tt.dest, err, tt.wantErr)
+			continue
+		}
+		if local != tt.local || got != filepath.FromSlash(tt.want) && tt.local {
+			t.Errorf("localImagePath(%q) = %q, %v, want %q, %v", tt.dest, got, local, tt.want, tt.local)
+		}
+	}
+}
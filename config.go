@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"craft-hackathon/client"
+)
+
+// DefaultListenAddr is where the server listens unless configured otherwise
+const DefaultListenAddr = "localhost:8080"
+
+// Config holds the settings of the server and the CLI
+type Config struct {
+	BaseURL    string      `json:"baseUrl"`
+	AuthToken  string      `json:"authToken,omitempty"`
+	Timeout    Duration    `json:"timeout,omitempty"` // Per request; zero means no limit
+	Retry      RetryConfig `json:"retry"`
+	ListenAddr string      `json:"listenAddr"`
+
+	// The rest only apply to the server
+	MaxBodyBytes    int64      `json:"maxBodyBytes"`
+	JobWorkers      int        `json:"jobWorkers"`
+	JobQueueSize    int        `json:"jobQueueSize"`
+	APITokens       []string   `json:"apiTokens,omitempty"` // Empty means no authentication
+	TimestampFormat string     `json:"timestampFormat"`     // A name from timeFormats or a layout
+	Timezone        string     `json:"timezone"`            // An IANA zone name such as "Europe/Berlin"
+	CORS            corsConfig `json:"cors"`
+}
+
+// RetryConfig mirrors client.RetryPolicy with durations readable in JSON
+type RetryConfig struct {
+	MaxAttempts int      `json:"maxAttempts"`
+	BaseDelay   Duration `json:"baseDelay"`
+	MaxDelay    Duration `json:"maxDelay"`
+	Jitter      string   `json:"jitter"` // "none", "full" or "equal"
+}
+
+// Duration is a time.Duration written in JSON as a string such as "30s"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads the JSON config file at path, if path is not empty, over
+// the defaults and then applies overrides from the environment:
+// CRAFT_BASE_URL, CRAFT_AUTH_TOKEN, CRAFT_TIMEOUT, RETRY_MAX_ATTEMPTS,
+// RETRY_JITTER, LISTEN_ADDR, MAX_BODY_BYTES, JOB_WORKERS, JOB_QUEUE_SIZE,
+// API_TOKENS, TIMESTAMP_FORMAT, TIMEZONE, CORS_ALLOWED_ORIGINS,
+// CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS. Lists in the environment
+// are comma-separated. Unknown fields in the file are an error.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{
+		BaseURL: BaseURL,
+		Retry: RetryConfig{
+			MaxAttempts: client.DefaultRetryPolicy.MaxAttempts,
+			BaseDelay:   Duration(client.DefaultRetryPolicy.BaseDelay),
+			MaxDelay:    Duration(client.DefaultRetryPolicy.MaxDelay),
+			Jitter:      client.DefaultRetryPolicy.Jitter,
+		},
+		ListenAddr:      DefaultListenAddr,
+		MaxBodyBytes:    DefaultMaxBodyBytes,
+		JobWorkers:      DefaultJobWorkers,
+		JobQueueSize:    DefaultJobQueueSize,
+		TimestampFormat: "RFC3339",
+		Timezone:        "UTC",
+		CORS: corsConfig{
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+		},
+	}
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening config: %w", err)
+		}
+		defer f.Close()
+
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("config has no base URL")
+	}
+	switch cfg.Retry.Jitter {
+	case "", client.JitterNone, client.JitterFull, client.JitterEqual:
+	default:
+		return nil, fmt.Errorf("invalid retry jitter %q", cfg.Retry.Jitter)
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		return nil, fmt.Errorf("invalid max body bytes %d", cfg.MaxBodyBytes)
+	}
+	if cfg.JobWorkers <= 0 {
+		return nil, fmt.Errorf("invalid job workers %d", cfg.JobWorkers)
+	}
+	if cfg.JobQueueSize <= 0 {
+		return nil, fmt.Errorf("invalid job queue size %d", cfg.JobQueueSize)
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (cfg *Config) applyEnv() error {
+	if v := os.Getenv("CRAFT_BASE_URL"); v != "" {
+		cfg.BaseURL = v
+	}
+	if v := os.Getenv("CRAFT_AUTH_TOKEN"); v != "" {
+		cfg.AuthToken = v
+	}
+	if v := os.Getenv("CRAFT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid CRAFT_TIMEOUT %q: %w", v, err)
+		}
+		cfg.Timeout = Duration(d)
+	}
+	if v := os.Getenv("RETRY_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid RETRY_MAX_ATTEMPTS %q: %w", v, err)
+		}
+		cfg.Retry.MaxAttempts = n
+	}
+	if v := os.Getenv("RETRY_JITTER"); v != "" {
+		cfg.Retry.Jitter = v
+	}
+	if v := os.Getenv("LISTEN_ADDR"); v != "" {
+		cfg.ListenAddr = v
+	}
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid MAX_BODY_BYTES %q: %w", v, err)
+		}
+		cfg.MaxBodyBytes = n
+	}
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid JOB_WORKERS %q: %w", v, err)
+		}
+		cfg.JobWorkers = n
+	}
+	if v := os.Getenv("JOB_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid JOB_QUEUE_SIZE %q: %w", v, err)
+		}
+		cfg.JobQueueSize = n
+	}
+	if v := splitList(os.Getenv("API_TOKENS")); len(v) > 0 {
+		cfg.APITokens = v
+	}
+	if v := os.Getenv("TIMESTAMP_FORMAT"); v != "" {
+		cfg.TimestampFormat = v
+	}
+	if v := os.Getenv("TIMEZONE"); v != "" {
+		cfg.Timezone = v
+	}
+	if v := splitList(os.Getenv("CORS_ALLOWED_ORIGINS")); len(v) > 0 {
+		cfg.CORS.AllowedOrigins = v
+	}
+	if v := splitList(os.Getenv("CORS_ALLOWED_METHODS")); len(v) > 0 {
+		cfg.CORS.AllowedMethods = v
+	}
+	if v := splitList(os.Getenv("CORS_ALLOWED_HEADERS")); len(v) > 0 {
+		cfg.CORS.AllowedHeaders = v
+	}
+	return nil
+}
+
+// NewClient creates a Craft API client with the configured settings
+func (cfg *Config) NewClient() *client.Client {
+	opts := []client.Option{
+		client.WithTimeout(time.Duration(cfg.Timeout)),
+		client.WithRetry(client.RetryPolicy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			BaseDelay:   time.Duration(cfg.Retry.BaseDelay),
+			MaxDelay:    time.Duration(cfg.Retry.MaxDelay),
+			Jitter:      cfg.Retry.Jitter,
+		}),
+	}
+	if cfg.AuthToken != "" {
+		opts = append(opts, client.WithAuthToken(cfg.AuthToken))
+	}
+	return client.NewClient(cfg.BaseURL, opts...)
+}
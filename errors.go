@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// ErrorResponse represents the JSON body of a failed request
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// writeError sends a JSON error response carrying the request ID
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID(r),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[%s] Error encoding error response: %v", requestID(r), err)
+	}
+}
+
+// bodyError returns 413 for a body over the size limit and 400 for any
+// other body error, with the matching error code
+func bodyError(err error) (int, string) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge, "body_too_large"
+	}
+	return http.StatusBadRequest, "invalid_body"
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"craft-hackathon/client"
+)
+
+const (
+	// DefaultJobWorkers is the number of workers inserting queued content
+	// unless configured otherwise
+	DefaultJobWorkers = 4
+
+	// DefaultJobQueueSize caps the number of waiting jobs unless configured
+	// otherwise
+	DefaultJobQueueSize = 100
+
+	// maxJobAttempts is how many times a worker tries an insert
+	maxJobAttempts = 3
+
+	// jobRetryDelay is the wait before the first retry, doubled after each
+	jobRetryDelay = time.Second
+
+	// jobRetention is how long finished jobs stay available for polling
+	jobRetention = time.Hour
+
+	// jobCleanupInterval is how often expired jobs are dropped
+	jobCleanupInterval = time.Minute
+)
+
+// Job states
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// errQueueFull is returned when a job cannot be queued
+var errQueueFull = errors.New("job queue is full")
+
+// errQueueClosed is returned when a job is queued after the queue was closed
+var errQueueClosed = errors.New("job queue is closed")
+
+// Job is an insert waiting for or handled by a worker, as reported by
+// GET /jobs/{id}
+type Job struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Query     string    `json:"query"`
+	BlockID   string    `json:"blockId,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// What to insert and where; a position without a page or sibling means
+	// the root page
+	insert    client.InsertRequest
+	requestID string
+}
+
+// jobQueue is a bounded queue of inserts drained by a pool of workers
+type jobQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	pending chan *Job
+	closed  bool
+
+	// workers tracks the goroutines draining pending
+	workers sync.WaitGroup
+
+	// done stops the cleanup of expired jobs
+	done chan struct{}
+}
+
+// newJobQueue creates a queue holding at most size waiting jobs and starts
+// dropping expired jobs in the background until the queue is closed
+func newJobQueue(size int) *jobQueue {
+	q := &jobQueue{
+		jobs:    make(map[string]*Job),
+		pending: make(chan *Job, size),
+		done:    make(chan struct{}),
+	}
+	go q.cleanup(jobCleanupInterval)
+	return q
+}
+
+// cleanup prunes expired jobs every interval until the queue is closed
+func (q *jobQueue) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.prune(time.Now())
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// prune drops finished jobs nobody polled within jobRetention of now
+func (q *jobQueue) prune(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, job := range q.jobs {
+		if (job.Status == jobSucceeded || job.Status == jobFailed) && now.Sub(job.UpdatedAt) > jobRetention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// close stops accepting jobs and waits for the workers to finish the ones
+// already queued
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.pending)
+	close(q.done)
+	q.mu.Unlock()
+
+	q.workers.Wait()
+}
+
+// enqueue queues a job and returns a snapshot of it, or errQueueFull or
+// errQueueClosed
+func (q *jobQueue) enqueue(job *Job) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Job{}, errQueueClosed
+	}
+
+	job.ID = randomID()
+	job.Status = jobQueued
+	job.CreatedAt = time.Now().UTC()
+	job.UpdatedAt = job.CreatedAt
+	select {
+	case q.pending <- job:
+	default:
+		return Job{}, errQueueFull
+	}
+	q.jobs[job.ID] = job
+	return *job, nil
+}
+
+// get returns a snapshot of the job with the given ID
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// update changes a job under the queue's lock
+func (q *jobQueue) update(job *Job, fn func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	fn(job)
+	job.UpdatedAt = time.Now().UTC()
+}
+
+// startWorkers starts n workers draining the job queue until it is closed
+func (s *server) startWorkers(n int) {
+	for i := 0; i < n; i++ {
+		s.jobs.workers.Add(1)
+		go func() {
+			defer s.jobs.workers.Done()
+			for job := range s.jobs.pending {
+				s.runJob(job)
+			}
+		}()
+	}
+}
+
+// runJob inserts a job's content, retrying failures that may be transient
+func (s *server) runJob(job *Job) {
+	delay := jobRetryDelay
+	for {
+		s.jobs.update(job, func(j *Job) {
+			j.Status = jobRunning
+			j.Attempts++
+		})
+
+		blockID, err := s.insertJob(job)
+		if err == nil {
+			s.jobs.update(job, func(j *Job) {
+				j.Status = jobSucceeded
+				j.BlockID = blockID
+			})
+			fmt.Printf("[%s] Job %s added content (%s) with block ID: %s\n", s.timestamp(), job.ID, describePosition(job.insert.Position), blockID)
+			return
+		}
+
+		var apiErr *client.APIError
+		permanent := errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests
+		if permanent || job.Attempts >= maxJobAttempts {
+			log.Printf("[%s] Job %s failed after %d attempt(s): %v", job.requestID, job.ID, job.Attempts, err)
+			s.jobs.update(job, func(j *Job) {
+				j.Status = jobFailed
+				j.Error = err.Error()
+			})
+			return
+		}
+
+		log.Printf("[%s] Job %s attempt %d failed, retrying in %s: %v", job.requestID, job.ID, job.Attempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// insertJob inserts a job's content and returns the new block's ID
+func (s *server) insertJob(job *Job) (string, error) {
+	req := job.insert
+	pos, err := withRootPage(req.Position, s.craft.RootPageID)
+	if err != nil {
+		return "", err
+	}
+	req.Position = pos
+
+	inserted, err := s.craft.InsertBlocks(req)
+	if err != nil {
+		return "", fmt.Errorf("adding content: %w", err)
+	}
+	if len(inserted) == 0 {
+		return "", errors.New("insert returned no blocks")
+	}
+	return inserted[0].ID, nil
+}
+
+// handleGetJob handles GET requests to /jobs/{id}
+func (s *server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "not_found", fmt.Sprintf("Job %s not found", r.PathValue("id")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("[%s] Error encoding response: %v", requestID(r), err)
+	}
+}
@@ -1,11 +1,23 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"craft-hackathon/client"
 )
@@ -13,198 +25,459 @@ import (
 const (
 	// API endpoint from the documentation
 	BaseURL = "https://connect.craft.do/links/3tXZdMX0EIe/api/v1"
+
+	// DefaultMaxBodyBytes caps request bodies unless configured otherwise
+	DefaultMaxBodyBytes = 1 << 20
+
+	// MaxQueryLength caps the number of characters in a query
+	MaxQueryLength = 10000
+
+	// shutdownTimeout is how long open requests get to finish on shutdown
+	shutdownTimeout = 30 * time.Second
 )
 
 // QueryRequest represents the incoming JSON payload
 type QueryRequest struct {
 	Query string `json:"query"`
+
+	// Format is "markdown" (the default) to insert the query as markdown,
+	// or "text" to escape it so it renders literally
+	Format string `json:"format,omitempty"`
+
+	// Optional placement of the content; it goes to the end of the root
+	// page when these are unset
+	PageID    string `json:"pageId,omitempty"`
+	Position  string `json:"position,omitempty"` // "start", "end", "before", "after"
+	SiblingID string `json:"siblingId,omitempty"`
+}
+
+// insertRequest builds the insert of markdown at the requested placement
+// with client.InsertRequestFromMarkdown. A start or end position without a
+// pageId leaves the page empty for the root page.
+func (q QueryRequest) insertRequest(markdown string) (client.InsertRequest, error) {
+	if q.PageID != "" && !blockIDPattern.MatchString(q.PageID) {
+		return client.InsertRequest{}, errors.New("invalid pageId")
+	}
+	if q.SiblingID != "" && !blockIDPattern.MatchString(q.SiblingID) {
+		return client.InsertRequest{}, errors.New("invalid siblingId")
+	}
+	if q.PageID != "" && q.SiblingID != "" {
+		return client.InsertRequest{}, errors.New("pageId and siblingId cannot both be set")
+	}
+
+	req, err := client.InsertRequestFromMarkdown(cmp.Or(q.SiblingID, q.PageID), markdown, q.Position)
+	if err != nil {
+		return req, err
+	}
+	if req.Position.SiblingID != q.SiblingID {
+		if q.SiblingID != "" {
+			return req, fmt.Errorf("siblingId is not allowed with position %q", req.Position.Position)
+		}
+		return req, fmt.Errorf("pageId is not allowed with position %q", req.Position.Position)
+	}
+	return req, nil
+}
+
+// withRootPage fills in the root page, looked up with rootPageID, for a
+// position without a page or sibling
+func withRootPage(pos client.Position, rootPageID func() (string, error)) (client.Position, error) {
+	if pos.PageID == "" && pos.SiblingID == "" {
+		root, err := rootPageID()
+		if err != nil {
+			return pos, err
+		}
+		pos.PageID = root
+	}
+	return pos, nil
+}
+
+// describePosition formats a position for logs, such as "end of root" or
+// "after abc"
+func describePosition(pos client.Position) string {
+	switch {
+	case pos.SiblingID != "":
+		return pos.Position + " " + pos.SiblingID
+	case pos.PageID != "":
+		return pos.Position + " of " + pos.PageID
+	}
+	return pos.Position + " of root"
 }
 
 // QueryResponse represents the response JSON
 type QueryResponse struct {
 	Status string `json:"status"`
 	Query  string `json:"query"`
+	JobID  string `json:"jobId,omitempty"`
+}
+
+// DeleteResponse represents the response JSON for block deletion
+type DeleteResponse struct {
+	Status   string   `json:"status"`
+	BlockIDs []string `json:"blockIds"`
+}
+
+// craftAPI is the part of the Craft client the server uses, satisfied by
+// both client.Client and client.FakeClient
+type craftAPI interface {
+	client.CraftAPI
+	DeleteSubtree(id string) ([]string, error)
+	Ping() error
+	RootPageID() (string, error)
+}
+
+// server holds the dependencies shared by the HTTP handlers
+type server struct {
+	craft        craftAPI
+	maxBodyBytes int64
+
+	// Log timestamps are written in this layout and time zone
+	timeFormat string
+	location   *time.Location
+
+	// Inserts are queued here and run by background workers
+	jobs *jobQueue
+}
+
+// timeFormats maps the names accepted as a timestamp format to layouts; any
+// other value is used as a layout itself
+var timeFormats = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"DateTime":    time.DateTime,
+	"Kitchen":     time.Kitchen,
+}
+
+// timestamp returns the current time in the configured layout and zone
+func (s *server) timestamp() string {
+	return time.Now().In(s.location).Format(s.timeFormat)
 }
 
 func main() {
-	// Set up HTTP handler
-	http.HandleFunc("/craft-hackathon", handleCraftHackathon)
+	// Settings come from the JSON file named by CONFIG_FILE, if any, with
+	// environment overrides
+	cfg, err := LoadConfig(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Loading config: %v", err)
+	}
+
+	// One client is shared by the CLI and all server requests; it is safe
+	// for concurrent use
+	c := cfg.NewClient()
+
+	// Any argument other than "serve" runs a CLI command instead of the server
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		code := runCLI(c, os.Args[1:], os.Stdout, os.Stderr)
+		c.Close()
+		os.Exit(code)
+	}
+
+	serve(c, cfg)
+}
+
+// serve runs the HTTP server on addr until it fails or the process is
+// interrupted, then lets open requests and queued jobs finish
+func serve(c *client.Client, cfg *Config) {
+	timeFormat := cfg.TimestampFormat
+	if layout, ok := timeFormats[timeFormat]; ok {
+		timeFormat = layout
+	}
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Fatalf("Invalid timezone %q: %v", cfg.Timezone, err)
+	}
+
+	s := &server{
+		craft:        c,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		timeFormat:   timeFormat,
+		location:     location,
+		jobs:         newJobQueue(cfg.JobQueueSize),
+	}
+	s.startWorkers(cfg.JobWorkers)
+
+	// Look up the root page now so inserts without a target can reuse it;
+	// if Craft is unreachable the first such insert tries again
+	if _, err := c.RootPageID(); err != nil {
+		log.Printf("Could not fetch root page ID: %v", err)
+	}
+
+	// Set up HTTP handlers
+	mux := http.NewServeMux()
+	mux.HandleFunc("/craft-hackathon", s.handleCraftHackathon)
+	mux.HandleFunc("PUT /craft-hackathon/blocks/{id}", s.handleUpdateBlock)
+	mux.HandleFunc("DELETE /craft-hackathon/blocks/{id}", s.handleDeleteBlock)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+
+	// Requests need one of the API tokens when any are set. CORS runs first
+	// so browser preflights, which carry no credentials, still succeed.
+	if len(cfg.APITokens) == 0 {
+		log.Printf("No API tokens are configured; the server accepts unauthenticated requests")
+	}
+	handler := withAuth(cfg.APITokens, mux)
+
+	// Browsers may only call the server from the configured origins
+	handler = withRequestID(withCORS(cfg.CORS, handler))
 
 	// Start server
-	addr := "localhost:8080"
-	fmt.Printf("Server starting on %s\n", addr)
+	fmt.Printf("Server starting on %s\n", cfg.ListenAddr)
 	fmt.Println("Listening for POST requests on /craft-hackathon")
+	fmt.Println("Polling job status on /jobs/{id}")
+	fmt.Println("Listening for PUT and DELETE requests on /craft-hackathon/blocks/{id}")
+	fmt.Println("Health checks on /healthz and /readyz")
+
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: handler}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		log.Printf("Shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}()
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server failed to start: %v", err)
 	}
+	<-shutdown
+
+	// Inserts already accepted with 202 are still made
+	s.jobs.close()
+}
+
+// handleHealthz reports that the process is up. Health checks are polled
+// often, so successful checks are not logged.
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "ok\n")
+}
+
+// handleReadyz reports whether the Craft API is reachable, returning 503
+// when it is not
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.craft.Ping(); err != nil {
+		log.Printf("[%s] Readiness check failed: %v", requestID(r), err)
+		writeError(w, r, http.StatusServiceUnavailable, "not_ready", fmt.Sprintf("Craft API unreachable: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "ok\n")
 }
 
 // handleCraftHackathon handles POST requests to /craft-hackathon
-func handleCraftHackathon(w http.ResponseWriter, r *http.Request) {
+func (s *server) handleCraftHackathon(w http.ResponseWriter, r *http.Request) {
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
 		return
 	}
 
-	// Parse JSON body
+	// Parse JSON body, refusing anything over the size limit
 	var req QueryRequest
-	decoder := json.NewDecoder(r.Body)
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
 	if err := decoder.Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		status, code := bodyError(err)
+		writeError(w, r, status, code, fmt.Sprintf("Invalid JSON: %v", err))
 		return
 	}
 
-	// Log the received query with timestamp
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	fmt.Printf("[%s] Received query: %s\n", timestamp, req.Query)
+	// Reject blank queries so they don't leave empty blocks behind
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		writeError(w, r, http.StatusBadRequest, "empty_query", "Query must not be empty")
+		return
+	}
+	if utf8.RuneCountInString(req.Query) > MaxQueryLength {
+		writeError(w, r, http.StatusBadRequest, "query_too_long", fmt.Sprintf("Query exceeds %d characters", MaxQueryLength))
+		return
+	}
 
-	// Create Craft API client
-	c := client.NewClient(BaseURL)
+	markdown := req.Query
+	switch req.Format {
+	case "", "markdown":
+	case "text":
+		markdown = client.EscapeMarkdown(req.Query)
+	default:
+		writeError(w, r, http.StatusBadRequest, "invalid_format", fmt.Sprintf("Invalid format %q", req.Format))
+		return
+	}
 
-	// Fetch the root document to get the actual root page ID
-	root, err := c.FetchBlocks("", 0, false)
+	insert, err := req.insertRequest(markdown)
 	if err != nil {
-		log.Printf("Error fetching root: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to fetch document: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusBadRequest, "invalid_position", err.Error())
 		return
 	}
 
-	// Simply insert the query text as a block at the end of the document
-	insertReq := client.InsertRequest{
-		Markdown: req.Query,
-		Position: client.Position{
-			Position: "end",
-			PageID:   root.ID, // Use actual root page ID
-		},
-	}
+	// Log the received query with timestamp
+	timestamp := s.timestamp()
+	fmt.Printf("[%s] Received query: %s\n", timestamp, req.Query)
 
-	insertedBlocks, err := c.InsertBlocks(insertReq)
+	// Hand the insert to the workers so the response does not wait on the
+	// Craft API
+	job, err := s.jobs.enqueue(&Job{
+		Query:     req.Query,
+		insert:    insert,
+		requestID: requestID(r),
+	})
+	if errors.Is(err, errQueueClosed) {
+		writeError(w, r, http.StatusServiceUnavailable, "shutting_down", "Server is shutting down")
+		return
+	}
 	if err != nil {
-		log.Printf("Error adding content: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to add content: %v", err), http.StatusInternalServerError)
+		w.Header().Set("Retry-After", "5")
+		writeError(w, r, http.StatusServiceUnavailable, "queue_full", "Too many pending requests, try again later")
 		return
 	}
+	fmt.Printf("[%s] Queued job %s\n", timestamp, job.ID)
 
-	blockID := insertedBlocks[0].ID
-	fmt.Printf("[%s] Added content to page %s with block ID: %s\n", timestamp, root.ID, blockID)
-
-	// Prepare success response
+	// Prepare accepted response
 	response := QueryResponse{
-		Status: "created",
+		Status: job.Status,
 		Query:  req.Query,
+		JobID:  job.ID,
 	}
 
 	// Send JSON response
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
+		log.Printf("[%s] Error encoding response: %v", requestID(r), err)
 	}
 }
 
-// ============================================================
-// COMMENTED OUT: Previous Craft API Explorer logic
-// Uncomment when ready to integrate with Craft API
-// ============================================================
-/*
-func mainOld() {
-	// Create the client
-	c := client.NewClient(BaseURL)
+// blockIDPattern matches a valid block ID path segment
+var blockIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 
-	fmt.Println("=== Craft API Explorer ===")
-	fmt.Println()
+// handleUpdateBlock handles PUT requests to /craft-hackathon/blocks/{id}.
+// The body is either raw markdown (Content-Type text/markdown) or a JSON
+// block whose set fields are applied to the block.
+func (s *server) handleUpdateBlock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !blockIDPattern.MatchString(id) {
+		writeError(w, r, http.StatusBadRequest, "invalid_block_id", "Invalid block ID")
+		return
+	}
 
-	// 1. Fetch the root document structure (shallow, just top level)
-	fmt.Println("1. Fetching root document structure (depth=1)...")
-	root, err := c.FetchBlocks("", 1, false)
-	if err != nil {
-		log.Fatalf("Failed to fetch root: %v", err)
-	}
-
-	fmt.Printf("   Document Title: %s\n", root.Markdown)
-	fmt.Printf("   Document Type: %s\n", root.Type)
-	fmt.Printf("   Text Style: %s\n", root.TextStyle)
-	fmt.Printf("   Number of top-level blocks: %d\n", len(root.Content))
-	fmt.Println()
-
-	// 2. List top-level blocks
-	fmt.Println("2. Top-level blocks:")
-	for i, block := range root.Content {
-		fmt.Printf("   [%d] ID: %s | Type: %s | Style: %s\n", i, block.ID, block.Type, block.TextStyle)
-		if block.Markdown != "" {
-			// Truncate long markdown
-			md := block.Markdown
-			if len(md) > 80 {
-				md = md[:77] + "..."
-			}
-			fmt.Printf("       Content: %s\n", md)
+	// Parse the update from the body, refusing anything over the size limit
+	var update client.Block
+	body := http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/markdown" || mediaType == "text/plain" {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			status, code := bodyError(err)
+			writeError(w, r, status, code, fmt.Sprintf("Failed to read body: %v", err))
+			return
 		}
+		update.Markdown = string(data)
+	} else if err := json.NewDecoder(body).Decode(&update); err != nil {
+		status, code := bodyError(err)
+		writeError(w, r, status, code, fmt.Sprintf("Invalid JSON: %v", err))
+		return
 	}
-	fmt.Println()
+	update.ID = id
 
-	// 3. Fetch full document as JSON (for inspection)
-	fmt.Println("3. Fetching full document structure...")
-	fullDoc, err := c.FetchBlocks("", -1, false)
-	if err != nil {
-		log.Fatalf("Failed to fetch full document: %v", err)
+	timestamp := s.timestamp()
+	fmt.Printf("[%s] Updating block %s\n", timestamp, id)
+
+	// Check the block exists so a missing block is a 404 rather than a
+	// failed update
+	if _, err := s.craft.FetchBlocks(id, 0, false); err != nil {
+		if client.IsNotFound(err) {
+			writeError(w, r, http.StatusNotFound, "not_found", fmt.Sprintf("Block %s not found", id))
+			return
+		}
+		log.Printf("[%s] Error fetching block %s: %v", requestID(r), id, err)
+		writeError(w, r, http.StatusInternalServerError, "fetch_failed", fmt.Sprintf("Failed to fetch block: %v", err))
+		return
 	}
 
-	// Count total blocks recursively
-	totalBlocks := countBlocks(fullDoc)
-	fmt.Printf("   Total blocks in document: %d\n", totalBlocks)
-	fmt.Println()
+	updated, err := s.craft.UpdateBlocks(client.UpdateRequest{Blocks: []client.Block{update}})
+	if err != nil || len(updated) == 0 {
+		log.Printf("[%s] Error updating block %s: %v", requestID(r), id, err)
+		writeError(w, r, http.StatusInternalServerError, "update_failed", fmt.Sprintf("Failed to update block: %v", err))
+		return
+	}
 
-	// 4. Test search functionality
-	fmt.Println("4. Testing search (looking for 'TODO' or 'task')...")
-	matches, err := c.Search("TODO|task", false, 2, 2)
-	if err != nil {
-		log.Printf("   Search failed: %v", err)
-	} else {
-		fmt.Printf("   Found %d matches\n", len(matches))
-		for i, match := range matches {
-			if i >= 3 { // Show first 3 only
-				fmt.Printf("   ... and %d more\n", len(matches)-3)
-				break
-			}
-			fmt.Printf("   [%d] Block %s: %s\n", i, match.BlockID, truncate(match.Markdown, 60))
-		}
+	fmt.Printf("[%s] Updated block %s\n", timestamp, id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(updated[0]); err != nil {
+		log.Printf("[%s] Error encoding response: %v", requestID(r), err)
 	}
-	fmt.Println()
+}
 
-	// 5. Export full structure as JSON for inspection
-	fmt.Println("5. Full document structure (JSON):")
-	jsonData, err := json.MarshalIndent(fullDoc, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal JSON: %v", err)
+// handleDeleteBlock handles DELETE requests to /craft-hackathon/blocks/{id}.
+// With ?recursive=true the block's whole subtree is deleted.
+func (s *server) handleDeleteBlock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !blockIDPattern.MatchString(id) {
+		writeError(w, r, http.StatusBadRequest, "invalid_block_id", "Invalid block ID")
+		return
 	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+
+	timestamp := s.timestamp()
+	fmt.Printf("[%s] Deleting block %s (recursive: %t)\n", timestamp, id, recursive)
 
-	// Just show first 2000 chars
-	jsonStr := string(jsonData)
-	if len(jsonStr) > 2000 {
-		fmt.Println(jsonStr[:2000])
-		fmt.Printf("\n... [truncated, full size: %d bytes]\n", len(jsonStr))
+	var deleted []string
+	var err error
+	if recursive {
+		deleted, err = s.craft.DeleteSubtree(id)
 	} else {
-		fmt.Println(jsonStr)
+		deleted, err = s.craft.DeleteBlocks([]string{id})
 	}
 
-	fmt.Println()
-	fmt.Println("=== Exploration Complete ===")
+	// A block that is already gone comes back as a 404 or as a partial
+	// success that did not include it
+	if client.IsNotFound(err) || (err == nil && !slices.Contains(deleted, id)) {
+		writeError(w, r, http.StatusNotFound, "not_found", fmt.Sprintf("Block %s not found", id))
+		return
+	}
+	var partial *client.PartialFailureError
+	if errors.As(err, &partial) && !slices.Contains(partial.Succeeded, id) {
+		writeError(w, r, http.StatusNotFound, "not_found", fmt.Sprintf("Block %s not found", id))
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] Error deleting block %s: %v", requestID(r), id, err)
+		writeError(w, r, http.StatusInternalServerError, "delete_failed", fmt.Sprintf("Failed to delete block: %v", err))
+		return
+	}
+
+	fmt.Printf("[%s] Deleted %d block(s) starting at %s\n", timestamp, len(deleted), id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(DeleteResponse{Status: "deleted", BlockIDs: deleted}); err != nil {
+		log.Printf("[%s] Error encoding response: %v", requestID(r), err)
+	}
 }
-*/
 
-// countBlocks recursively counts all blocks
+// countBlocks counts a block and all of its descendants
 func countBlocks(block *client.Block) int {
 	if block == nil {
 		return 0
 	}
-	count := 1
-	for i := range block.Content {
-		count += countBlocks(&block.Content[i])
-	}
-	return count
+	return len(block.Flatten())
 }
 
 // truncate shortens a string if needed
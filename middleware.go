@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// corsConfig controls which cross-origin browser requests the server allows
+type corsConfig struct {
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"` // "*" allows any origin; empty means same-origin only
+	AllowedMethods []string `json:"allowedMethods"`
+	AllowedHeaders []string `json:"allowedHeaders"`
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// allowsOrigin reports whether requests from origin are allowed
+func (cfg corsConfig) allowsOrigin(origin string) bool {
+	return slices.Contains(cfg.AllowedOrigins, "*") || slices.Contains(cfg.AllowedOrigins, origin)
+}
+
+// withCORS adds CORS headers for allowed origins and answers preflight
+// OPTIONS requests without passing them on
+func withCORS(cfg corsConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && cfg.allowsOrigin(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// withRequestID tags each request with an ID, taken from the X-Request-ID
+// header when the caller supplies one, and echoes it in the response
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = randomID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// randomID returns a random 16-character hex ID
+func randomID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestID returns the ID assigned to the request by withRequestID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// authExemptPaths are reachable without credentials so load balancers can
+// run health checks
+var authExemptPaths = []string{"/healthz", "/readyz"}
+
+// withAuth rejects requests that do not present one of tokens, either as
+// "Authorization: Bearer <token>" or in an X-API-Key header. With no tokens
+// configured every request is let through.
+func withAuth(tokens []string, next http.Handler) http.Handler {
+	if len(tokens) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(authExemptPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presented := r.Header.Get("X-API-Key")
+		// The auth scheme is case-insensitive, so "bearer" works too
+		if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			presented = strings.TrimSpace(token)
+		}
+		if presented == "" || !validToken(tokens, presented) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="craft-hackathon"`)
+			writeError(w, r, http.StatusUnauthorized, "unauthorized", "Missing or invalid credentials")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validToken compares the presented token against every configured one in
+// constant time
+func validToken(tokens []string, presented string) bool {
+	valid := false
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(presented)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithAuth(t *testing.T) {
+	handler := withAuth([]string{"secret"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"bearer", "/query", "Authorization", "Bearer secret", http.StatusNoContent},
+		{"lowercase bearer", "/query", "Authorization", "bearer secret", http.StatusNoContent},
+		{"uppercase bearer", "/query", "Authorization", "BEARER secret", http.StatusNoContent},
+		{"api key", "/query", "X-API-Key", "secret", http.StatusNoContent},
+		{"wrong token", "/query", "Authorization", "Bearer other", http.StatusUnauthorized},
+		{"other scheme", "/query", "Authorization", "Basic secret", http.StatusUnauthorized},
+		{"missing", "/query", "", "", http.StatusUnauthorized},
+		{"exempt path", "/healthz", "", "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}